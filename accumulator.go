@@ -0,0 +1,225 @@
+package aisuite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ChatCompletionAccumulator assembles the chunks of a ChatCompletionStream
+// into a ChatCompletionResponse of the same shape as the one returned by
+// ChatCompletion.
+type ChatCompletionAccumulator struct {
+	// ResponseFormat is the response format of the request, the JSON of
+	// the choices is decoded by Response when it asks for JSON.
+	ResponseFormat *ResponseFormat
+
+	id       string
+	model    string
+	choices  []choiceAccumulator
+	usage    Usage
+	metadata ResponseMetadata
+}
+
+type choiceAccumulator struct {
+	index        int
+	role         Role
+	content      string
+	refusal      string
+	toolCalls    ToolCallAccumulator
+	finishReason FinishReason
+}
+
+// Add merges a stream chunk into the accumulated response.
+func (a *ChatCompletionAccumulator) Add(chunk ChatCompletionStreamResponse) {
+	if chunk.ID != "" {
+		a.id = chunk.ID
+	}
+	if chunk.Model != "" {
+		a.model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		a.usage = *chunk.Usage
+	}
+	if chunk.Metadata.Model != "" {
+		a.metadata.Model = chunk.Metadata.Model
+	}
+	if chunk.Metadata.Cost != 0 {
+		a.metadata.Cost = chunk.Metadata.Cost
+	}
+	if chunk.Metadata.Cached {
+		a.metadata.Cached = true
+	}
+	for _, choice := range chunk.Choices {
+		c := a.choice(choice.Index)
+		if choice.Delta.Role != "" {
+			c.role = choice.Delta.Role
+		}
+		c.content += choice.Delta.Content
+		c.refusal += choice.Delta.Refusal
+		c.toolCalls.Add(choice.Delta.ToolCalls...)
+		if choice.FinishReason != FinishReasonNone {
+			c.finishReason = choice.FinishReason
+		}
+	}
+}
+
+func (a *ChatCompletionAccumulator) choice(index int) *choiceAccumulator {
+	for i := range a.choices {
+		if a.choices[i].index == index {
+			return &a.choices[i]
+		}
+	}
+	a.choices = append(a.choices, choiceAccumulator{index: index})
+	return &a.choices[len(a.choices)-1]
+}
+
+// Snapshot returns the response accumulated so far. Tool call arguments may
+// be incomplete JSON.
+func (a *ChatCompletionAccumulator) Snapshot() ChatCompletionResponse {
+	resp, _ := a.response(false)
+	return *resp
+}
+
+// Response returns the accumulated response, it should be called after the
+// stream is finished. An error is returned if any tool call arguments are
+// not valid JSON.
+func (a *ChatCompletionAccumulator) Response() (*ChatCompletionResponse, error) {
+	return a.response(true)
+}
+
+func (a *ChatCompletionAccumulator) response(complete bool) (*ChatCompletionResponse, error) {
+	choices := make([]ChatCompletionChoice, len(a.choices))
+	for i := range a.choices {
+		c := &a.choices[i]
+		toolCalls := c.toolCalls.Snapshot()
+		if complete {
+			var err error
+			if toolCalls, err = c.toolCalls.ToolCalls(); err != nil {
+				return nil, err
+			}
+		}
+		role := c.role
+		if role == "" {
+			role = RoleAssistant
+		}
+		choices[i] = ChatCompletionChoice{
+			Index: c.index,
+			Message: ChatCompletionMessage{
+				Role:      role,
+				Content:   c.content,
+				ToolCalls: toolCalls,
+				Refusal:   c.refusal,
+			},
+			FinishReason: c.finishReason,
+		}
+		if complete && a.ResponseFormat.IsJSON() {
+			choices[i].JSON, _ = ExtractJSON(c.content)
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].Index < choices[j].Index })
+	return &ChatCompletionResponse{
+		ID:       a.id,
+		Model:    a.model,
+		Choices:  choices,
+		Usage:    a.usage,
+		Metadata: a.metadata,
+	}, nil
+}
+
+// AccumulateStream reads stream until io.EOF and returns the accumulated
+// response. If onChunk is not nil, it is called with every chunk as it
+// arrives, e.g. to render the response while it is generated. The stream is
+// not closed.
+func AccumulateStream(stream ChatCompletionStream, onChunk func(chunk ChatCompletionStreamResponse)) (*ChatCompletionResponse, error) {
+	return AccumulateStreamFormat(stream, nil, onChunk)
+}
+
+// AccumulateStreamFormat is AccumulateStream for a request with a response
+// format, the JSON of the choices is decoded when it asks for JSON.
+func AccumulateStreamFormat(stream ChatCompletionStream, format *ResponseFormat, onChunk func(chunk ChatCompletionStreamResponse)) (*ChatCompletionResponse, error) {
+	acc := ChatCompletionAccumulator{ResponseFormat: format}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return acc.Response()
+		}
+		if err != nil {
+			return nil, err
+		}
+		acc.Add(chunk)
+		if onChunk != nil {
+			onChunk(chunk)
+		}
+	}
+}
+
+// ToolCallAccumulator assembles the tool call deltas of a stream into complete
+// tool calls. Deltas are matched by Index: the first delta of a tool call
+// carries its ID and function name, the following ones carry fragments of the
+// arguments.
+type ToolCallAccumulator struct {
+	calls []ToolCall
+}
+
+// Add merges tool call deltas into the accumulated tool calls.
+func (a *ToolCallAccumulator) Add(deltas ...ToolCall) {
+	for _, delta := range deltas {
+		call := a.find(delta.Index)
+		// Some OpenAI compatible providers send every tool call complete and
+		// without index, a new ID starts a new tool call.
+		if call == nil || (delta.ID != "" && call.ID != "" && delta.ID != call.ID) {
+			a.calls = append(a.calls, ToolCall{Index: delta.Index})
+			call = &a.calls[len(a.calls)-1]
+		}
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Tool != "" {
+			call.Tool = delta.Tool
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		call.Function.Args += delta.Function.Args
+	}
+}
+
+func (a *ToolCallAccumulator) find(index int) *ToolCall {
+	for i := len(a.calls) - 1; i >= 0; i-- {
+		if a.calls[i].Index == index {
+			return &a.calls[i]
+		}
+	}
+	return nil
+}
+
+// Snapshot returns the tool calls accumulated so far, arguments may be
+// incomplete JSON.
+func (a *ToolCallAccumulator) Snapshot() []ToolCall {
+	if len(a.calls) == 0 {
+		return nil
+	}
+	calls := make([]ToolCall, len(a.calls))
+	copy(calls, a.calls)
+	sort.SliceStable(calls, func(i, j int) bool { return calls[i].Index < calls[j].Index })
+	return calls
+}
+
+// ToolCalls returns the complete tool calls ordered by index. Empty arguments
+// are returned as "{}", an error is returned if any arguments are not valid
+// JSON.
+func (a *ToolCallAccumulator) ToolCalls() ([]ToolCall, error) {
+	calls := a.Snapshot()
+	for i := range calls {
+		call := &calls[i]
+		if call.Function.Args == "" {
+			call.Function.Args = "{}"
+		} else if !json.Valid([]byte(call.Function.Args)) {
+			return nil, fmt.Errorf("aisuite: tool call %q (%s) has invalid JSON arguments: %s", call.ID, call.Function.Name, call.Function.Args)
+		}
+	}
+	return calls, nil
+}
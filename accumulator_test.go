@@ -0,0 +1,162 @@
+package aisuite
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestToolCallAccumulator(t *testing.T) {
+	var acc ToolCallAccumulator
+	acc.Add(
+		ToolCall{Index: 0, ID: "call_1", Tool: "function", Function: FunctionCall{Name: "get_weather"}},
+		ToolCall{Index: 1, ID: "call_2", Tool: "function", Function: FunctionCall{Name: "get_time"}},
+	)
+	acc.Add(ToolCall{Index: 0, Function: FunctionCall{Args: `{"city":`}})
+	acc.Add(ToolCall{Index: 0, Function: FunctionCall{Args: `"Paris"}`}})
+
+	if got := acc.Snapshot(); len(got) != 2 || got[0].Function.Args != `{"city":"Paris"}` {
+		t.Errorf("unexpected snapshot %+v", got)
+	}
+
+	got, err := acc.ToolCalls()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ToolCall{
+		{Index: 0, ID: "call_1", Tool: "function", Function: FunctionCall{Name: "get_weather", Args: `{"city":"Paris"}`}},
+		{Index: 1, ID: "call_2", Tool: "function", Function: FunctionCall{Name: "get_time", Args: `{}`}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestToolCallAccumulatorNewID(t *testing.T) {
+	var acc ToolCallAccumulator
+	acc.Add(ToolCall{ID: "call_1", Function: FunctionCall{Name: "a", Args: `{}`}})
+	acc.Add(ToolCall{ID: "call_2", Function: FunctionCall{Name: "b", Args: `{}`}})
+	got, err := acc.ToolCalls()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "call_1" || got[1].ID != "call_2" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestToolCallAccumulatorInvalidJSON(t *testing.T) {
+	var acc ToolCallAccumulator
+	acc.Add(ToolCall{ID: "call_1", Function: FunctionCall{Name: "a", Args: `{"city":`}})
+	if _, err := acc.ToolCalls(); err == nil {
+		t.Error("got nil error for incomplete arguments")
+	}
+}
+
+type sliceStream struct {
+	chunks []ChatCompletionStreamResponse
+}
+
+func (s *sliceStream) Recv() (ChatCompletionStreamResponse, error) {
+	if len(s.chunks) == 0 {
+		return ChatCompletionStreamResponse{}, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *sliceStream) Close() error {
+	return nil
+}
+
+func TestAccumulateStream(t *testing.T) {
+	stream := &sliceStream{chunks: []ChatCompletionStreamResponse{
+		{ID: "chatcmpl-1", Model: "gpt-4o-mini", Choices: []ChatCompletionStreamChoice{
+			{Index: 0, Delta: ChatCompletionStreamChoiceDelta{Role: RoleAssistant, Content: "Hello"}},
+			{Index: 1, Delta: ChatCompletionStreamChoiceDelta{Role: RoleAssistant, Refusal: "I can't"}},
+		}},
+		{ID: "chatcmpl-1", Model: "gpt-4o-mini", Choices: []ChatCompletionStreamChoice{
+			{Index: 0, Delta: ChatCompletionStreamChoiceDelta{Content: ", world"}},
+			{Index: 1, Delta: ChatCompletionStreamChoiceDelta{Refusal: " help."}},
+		}},
+		{ID: "chatcmpl-1", Model: "gpt-4o-mini", Choices: []ChatCompletionStreamChoice{
+			{Index: 0, Delta: ChatCompletionStreamChoiceDelta{ToolCalls: []ToolCall{{ID: "call_1", Tool: "function", Function: FunctionCall{Name: "get_weather"}}}}},
+		}},
+		{ID: "chatcmpl-1", Model: "gpt-4o-mini", Choices: []ChatCompletionStreamChoice{
+			{Index: 0, Delta: ChatCompletionStreamChoiceDelta{ToolCalls: []ToolCall{{Function: FunctionCall{Args: `{"city":"Paris"}`}}}}},
+		}},
+		{ID: "chatcmpl-1", Model: "gpt-4o-mini", Choices: []ChatCompletionStreamChoice{
+			{Index: 1, FinishReason: FinishReasonContentFilter},
+			{Index: 0, FinishReason: FinishReasonToolCalls},
+		}},
+		{ID: "chatcmpl-1", Model: "gpt-4o-mini", Usage: &Usage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30}},
+	}}
+
+	var chunks int
+	var acc ChatCompletionAccumulator
+	resp, err := AccumulateStream(stream, func(chunk ChatCompletionStreamResponse) {
+		chunks++
+		acc.Add(chunk)
+		if chunks == 2 {
+			snapshot := acc.Snapshot()
+			if got := snapshot.Choices[0].Message.Content; got != "Hello, world" {
+				t.Errorf("got snapshot content %q", got)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks != 6 {
+		t.Errorf("got %d chunks, want 6", chunks)
+	}
+	want := &ChatCompletionResponse{
+		ID:    "chatcmpl-1",
+		Model: "gpt-4o-mini",
+		Choices: []ChatCompletionChoice{
+			{
+				Index: 0,
+				Message: ChatCompletionMessage{
+					Role:    RoleAssistant,
+					Content: "Hello, world",
+					ToolCalls: []ToolCall{
+						{ID: "call_1", Tool: "function", Function: FunctionCall{Name: "get_weather", Args: `{"city":"Paris"}`}},
+					},
+				},
+				FinishReason: FinishReasonToolCalls,
+			},
+			{
+				Index:        1,
+				Message:      ChatCompletionMessage{Role: RoleAssistant, Refusal: "I can't help."},
+				FinishReason: FinishReasonContentFilter,
+			},
+		},
+		Usage: Usage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30},
+	}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("got  %+v\nwant %+v", resp, want)
+	}
+}
+
+func TestAccumulateStreamJSON(t *testing.T) {
+	chunks := []ChatCompletionStreamResponse{
+		{Choices: []ChatCompletionStreamChoice{{Delta: ChatCompletionStreamChoiceDelta{Role: RoleAssistant, Content: `{"answer":`}}}},
+		{Choices: []ChatCompletionStreamChoice{{Delta: ChatCompletionStreamChoiceDelta{Content: ` 42}`}, FinishReason: FinishReasonStop}}},
+	}
+	resp, err := AccumulateStreamFormat(&sliceStream{chunks: chunks}, &ResponseFormat{Type: ResponseFormatTypeJSONObject}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(resp.Choices[0].JSON); got != `{"answer": 42}` {
+		t.Errorf("got JSON %s, want the content", got)
+	}
+
+	resp, err = AccumulateStream(&sliceStream{chunks: chunks}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].JSON != nil {
+		t.Errorf("got JSON %s without JSON response format", resp.Choices[0].JSON)
+	}
+}
@@ -0,0 +1,269 @@
+// Package cache caches chat completion responses.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+)
+
+// Store stores encoded responses by key. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Get returns the value of key, false if it's missing or expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value of key, it expires after ttl unless ttl is zero.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+type Options struct {
+	// TTL is how long responses are cached, zero caches them forever.
+	TTL time.Duration
+	// OnError is called when the store fails, e.g. to log it. The cache
+	// is best-effort: read failures are misses and responses that can't be
+	// stored are returned anyway.
+	OnError func(err error)
+}
+
+type Option func(o Options) Options
+
+func WithTTL(ttl time.Duration) Option {
+	return func(o Options) Options {
+		o.TTL = ttl
+		return o
+	}
+}
+
+func WithOnError(onError func(err error)) Option {
+	return func(o Options) Options {
+		o.OnError = onError
+		return o
+	}
+}
+
+// Client caches the responses of a client in a store. Requests are keyed
+// by Key, streamed and unary requests share cache entries and cached
+// responses are replayed as streams.
+//
+// Errors are never cached, and cached responses have Metadata.Cached set.
+// Store failures don't fail requests, see WithOnError.
+type Client struct {
+	client aisuite.Client
+	store  Store
+	opts   Options
+}
+
+func New(client aisuite.Client, store Store, opts ...Option) *Client {
+	o := Options{}
+	for _, opt := range opts {
+		o = opt(o)
+	}
+	return &Client{client: client, store: store, opts: o}
+}
+
+// Middleware returns a middleware caching responses in store, see New.
+func Middleware(store Store, opts ...Option) aisuite.Middleware {
+	return func(next aisuite.Client) aisuite.Client {
+		return New(next, store, opts...)
+	}
+}
+
+func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	key, err := Key(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp, ok := c.get(ctx, key); ok {
+		return resp, nil
+	}
+	resp, err := c.client.ChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c.set(ctx, key, resp)
+	return resp, nil
+}
+
+func (c *Client) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
+	key, err := Key(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp, ok := c.get(ctx, key); ok {
+		return newReplayStream(resp), nil
+	}
+	stream, err := c.client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s := &recordStream{ChatCompletionStream: stream, client: c, ctx: ctx, key: key}
+	s.accumulator.ResponseFormat = req.ResponseFormat
+	return s, nil
+}
+
+// get returns the cached response of key, failures are reported to
+// OnError and missed.
+func (c *Client) get(ctx context.Context, key string) (*aisuite.ChatCompletionResponse, bool) {
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil || !ok {
+		c.report(err)
+		return nil, false
+	}
+	var resp aisuite.ChatCompletionResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		// Entries of incompatible versions are missed.
+		return nil, false
+	}
+	for i := range resp.Choices {
+		// A nil json.RawMessage is encoded as null.
+		if string(resp.Choices[i].JSON) == "null" {
+			resp.Choices[i].JSON = nil
+		}
+	}
+	resp.Metadata.Cached = true
+	return &resp, true
+}
+
+// set stores resp, failures are reported to OnError.
+func (c *Client) set(ctx context.Context, key string, resp *aisuite.ChatCompletionResponse) {
+	data, err := json.Marshal(resp)
+	if err == nil {
+		err = c.store.Set(ctx, key, data, c.opts.TTL)
+	}
+	c.report(err)
+}
+
+func (c *Client) report(err error) {
+	if err != nil && c.opts.OnError != nil {
+		c.opts.OnError(err)
+	}
+}
+
+// Key returns the cache key of a request, the SHA-256 of its normalized JSON
+// encoding. Requests differing only by Stream, nil or empty fields, or the
+// representation of text content have the same key.
+func Key(req aisuite.ChatCompletionRequest) (string, error) {
+	req.Stream = false
+	messages := make([]aisuite.ChatCompletionMessage, len(req.Messages))
+	for i, msg := range req.Messages {
+		msg.MultiContent = msg.Parts()
+		msg.Content = ""
+		messages[i] = msg
+	}
+	req.Messages = messages
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+	if data, err = json.Marshal(normalize(v)); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalize drops null values and empty arrays and objects, encoding/json
+// sorts the keys of objects.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			value = normalize(value)
+			if value == nil {
+				delete(v, key)
+			} else {
+				v[key] = value
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+	case []any:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+		if len(v) == 0 {
+			return nil
+		}
+	}
+	return v
+}
+
+// recordStream stores the response of the stream once it ends.
+type recordStream struct {
+	aisuite.ChatCompletionStream
+	client      *Client
+	ctx         context.Context
+	key         string
+	accumulator aisuite.ChatCompletionAccumulator
+	done        bool
+}
+
+func (s *recordStream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
+	chunk, err := s.ChatCompletionStream.Recv()
+	if err == io.EOF && !s.done {
+		s.done = true
+		if resp, accErr := s.accumulator.Response(); accErr != nil {
+			s.client.report(accErr)
+		} else {
+			s.client.set(s.ctx, s.key, resp)
+		}
+	}
+	if err == nil {
+		s.accumulator.Add(chunk)
+	}
+	return chunk, err
+}
+
+// replayStream replays a response as a stream: a chunk with the message
+// of every choice, then a chunk with the finish reasons and usage.
+type replayStream struct {
+	chunks []aisuite.ChatCompletionStreamResponse
+}
+
+func newReplayStream(resp *aisuite.ChatCompletionResponse) *replayStream {
+	s := &replayStream{}
+	last := aisuite.ChatCompletionStreamResponse{ID: resp.ID, Model: resp.Model, Usage: &resp.Usage, Metadata: resp.Metadata}
+	for _, choice := range resp.Choices {
+		msg := choice.Message
+		s.chunks = append(s.chunks, aisuite.ChatCompletionStreamResponse{
+			ID:    resp.ID,
+			Model: resp.Model,
+			Choices: []aisuite.ChatCompletionStreamChoice{{
+				Index: choice.Index,
+				Delta: aisuite.ChatCompletionStreamChoiceDelta{
+					Role:      msg.Role,
+					Content:   msg.Content,
+					ToolCalls: msg.ToolCalls,
+					Refusal:   msg.Refusal,
+				},
+			}},
+			Metadata: resp.Metadata,
+		})
+		last.Choices = append(last.Choices, aisuite.ChatCompletionStreamChoice{Index: choice.Index, FinishReason: choice.FinishReason})
+	}
+	s.chunks = append(s.chunks, last)
+	return s
+}
+
+func (s *replayStream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
+	if len(s.chunks) == 0 {
+		return aisuite.ChatCompletionStreamResponse{}, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *replayStream) Close() error {
+	return nil
+}
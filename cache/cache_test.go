@@ -0,0 +1,237 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers/mock"
+)
+
+var fakeResponse = aisuite.ChatCompletionResponse{
+	ID:    "chatcmpl-1",
+	Model: "gpt-4o-mini-2024-07-18",
+	Choices: []aisuite.ChatCompletionChoice{{
+		Message: aisuite.ChatCompletionMessage{
+			Role:    aisuite.RoleAssistant,
+			Content: "Let me check.",
+			ToolCalls: []aisuite.ToolCall{{
+				ID: "call_1", Tool: "function",
+				Function: aisuite.FunctionCall{Name: "get_weather", Args: `{"city":"Paris"}`},
+			}},
+		},
+		FinishReason: aisuite.FinishReasonToolCalls,
+	}},
+	Usage: aisuite.Usage{PromptTokens: 9, CompletionTokens: 3, TotalTokens: 12},
+}
+
+// fakeClient replies fakeResponse n times.
+func fakeClient(n int) *mock.Client {
+	fake := mock.NewClient()
+	for i := 0; i < n; i++ {
+		fake.Add(mock.Step{Response: &fakeResponse})
+	}
+	return fake
+}
+
+func TestKey(t *testing.T) {
+	req := aisuite.ChatCompletionRequest{
+		Model:    "openai:gpt-4o-mini",
+		Messages: []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: "Hi"}},
+		Tools:    []aisuite.Tool{{Name: "get_weather", Parameters: map[string]any{"type": "object", "properties": map[string]any{}}}},
+	}
+	same := req
+	same.Stream = true
+	same.Messages = []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, MultiContent: []aisuite.ContentPart{aisuite.NewTextPart("Hi")}}}
+	same.Stop = []string{}
+	same.Tools = []aisuite.Tool{{Name: "get_weather", Parameters: map[string]any{"properties": map[string]any{}, "type": "object"}}}
+	different := req
+	different.Temperature = aisuite.Ptr(0.0)
+
+	key, err := Key(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, _ := Key(same); k != key {
+		t.Error("equivalent requests have different keys")
+	}
+	if k, _ := Key(different); k == key {
+		t.Error("different requests have the same key")
+	}
+}
+
+func TestClient(t *testing.T) {
+	dir, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]Store{"memory": NewMemoryStore(10), "disk": dir}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			fake := fakeClient(2)
+			client := New(fake, store, WithTTL(time.Hour))
+			ctx := context.Background()
+			req := aisuite.ChatCompletionRequest{
+				Model:    "openai:gpt-4o-mini",
+				Messages: []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: name}},
+			}
+
+			resp, err := client.ChatCompletion(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Metadata.Cached {
+				t.Error("first response is cached")
+			}
+			cached, err := client.ChatCompletion(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cached.Metadata.Cached {
+				t.Error("second response is not cached")
+			}
+			cached.Metadata = resp.Metadata
+			if !reflect.DeepEqual(cached, resp) {
+				t.Errorf("got cached response %+v, want %+v", cached, resp)
+			}
+
+			// The cached response is replayed as a stream.
+			stream, err := client.StreamChatCompletion(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			streamed, err := aisuite.AccumulateStream(stream, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !streamed.Metadata.Cached {
+				t.Error("stream is not cached")
+			}
+			streamed.Metadata = resp.Metadata
+			if !reflect.DeepEqual(streamed, resp) {
+				t.Errorf("got streamed response %+v, want %+v", streamed, resp)
+			}
+			if calls := len(fake.Requests()); calls != 1 {
+				t.Errorf("got %d calls, want 1", calls)
+			}
+
+			// Streams are recorded once finished.
+			req.Messages[0].Content += " stream"
+			for i := 0; i < 2; i++ {
+				stream, err := client.StreamChatCompletion(ctx, req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for {
+					if _, err = stream.Recv(); err == io.EOF {
+						break
+					}
+				}
+			}
+			if calls := len(fake.Requests()); calls != 2 {
+				t.Errorf("got %d calls, want 2", calls)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+	_ = store.Set(ctx, "a", []byte("1"), 0)
+	_ = store.Set(ctx, "b", []byte("2"), 0)
+	_, _, _ = store.Get(ctx, "a")
+	_ = store.Set(ctx, "c", []byte("3"), 0)
+	if _, ok, _ := store.Get(ctx, "b"); ok {
+		t.Error("least recently used entry not evicted")
+	}
+	if v, ok, _ := store.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Error("recently used entry evicted")
+	}
+	_ = store.Set(ctx, "d", []byte("4"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok, _ := store.Get(ctx, "d"); ok {
+		t.Error("expired entry returned")
+	}
+}
+
+func TestDiskStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Set(ctx, "a", []byte("1"), time.Nanosecond)
+	_ = store.Set(ctx, "b", []byte("2"), 0)
+	time.Sleep(time.Millisecond)
+	if _, ok, _ := store.Get(ctx, "a"); ok {
+		t.Error("expired entry returned")
+	}
+	if v, ok, err := store.Get(ctx, "b"); !ok || err != nil || string(v) != "2" {
+		t.Errorf("got %q, %v, %v", v, ok, err)
+	}
+}
+
+// failingStore misses every Get, failing with getErr, and fails every Set
+// with setErr.
+type failingStore struct {
+	getErr, setErr error
+}
+
+func (s failingStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, s.getErr
+}
+
+func (s failingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.setErr
+}
+
+func TestClientStoreError(t *testing.T) {
+	errGet, errSet := errors.New("disk read error"), errors.New("disk write error")
+	stores := map[string]failingStore{"get": {getErr: errGet}, "set": {setErr: errSet}}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			var errs []error
+			fake := fakeClient(2)
+			client := New(fake, store, WithOnError(func(err error) { errs = append(errs, err) }))
+			ctx := context.Background()
+			req := aisuite.ChatCompletionRequest{
+				Model:    "openai:gpt-4o-mini",
+				Messages: []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: "Hi"}},
+			}
+
+			resp, err := client.ChatCompletion(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*resp, fakeResponse) {
+				t.Errorf("got response %+v, want %+v", resp, fakeResponse)
+			}
+
+			stream, err := client.StreamChatCompletion(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := aisuite.AccumulateStream(stream, nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := stream.Recv(); err != io.EOF {
+				t.Errorf("got error %v after the end of the stream, want io.EOF", err)
+			}
+			if calls := len(fake.Requests()); calls != 2 {
+				t.Errorf("got %d calls, want 2", calls)
+			}
+			want := store.getErr
+			if want == nil {
+				want = store.setErr
+			}
+			if len(errs) != 2 || !errors.Is(errs[0], want) || !errors.Is(errs[1], want) {
+				t.Errorf("got errors %v, want 2 times %v", errs, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory Store evicting the least recently used
+// entries beyond its capacity.
+type MemoryStore struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryStore returns a store of at most capacity entries, zero is
+// unbounded.
+func NewMemoryStore(capacity int) *MemoryStore {
+	return &MemoryStore{capacity: capacity, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*memoryEntry)
+	if expired(entry.expiresAt) {
+		s.lru.Remove(elem)
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+	s.lru.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := &memoryEntry{key: key, value: value, expiresAt: expiresAt(ttl)}
+	if elem, ok := s.entries[key]; ok {
+		elem.Value = entry
+		s.lru.MoveToFront(elem)
+		return nil
+	}
+	s.entries[key] = s.lru.PushFront(entry)
+	if s.capacity > 0 && s.lru.Len() > s.capacity {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not evicted
+// yet.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// DiskStore is a Store keeping an entry per file in a directory, it can be
+// shared by processes.
+type DiskStore struct {
+	dir string
+}
+
+type diskEntry struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Value     []byte    `json:"value"`
+}
+
+// NewDiskStore returns a store in dir, it's created if missing.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+func (s *DiskStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+func (s *DiskStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var entry diskEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// A corrupted entry is a miss, it's overwritten by the next Set.
+		return nil, false, nil
+	}
+	if expired(entry.ExpiresAt) {
+		_ = os.Remove(s.path(key))
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+func (s *DiskStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	data, err := json.Marshal(diskEntry{ExpiresAt: expiresAt(ttl), Value: value})
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file renamed in place, so readers never see a
+	// partial entry.
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	tmp := s.path(key) + "." + hex.EncodeToString(suffix) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path(key)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func expiresAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func expired(expiresAt time.Time) bool {
+	return !expiresAt.IsZero() && time.Now().After(expiresAt)
+}
@@ -0,0 +1,337 @@
+// Package cassette records HTTP interactions with providers to fixture
+// files and replays them, so clients can be tested without network or API
+// keys.
+//
+// A recorder is injected with providers.Options.HTTPClient or
+// client.WithHTTPClient:
+//
+//	rec, err := cassette.New("testdata/chat.json", cassette.WithMode(cassette.ModeReplay))
+//	...
+//	defer rec.Save()
+//	c := client.New(nil, client.WithHTTPClient(rec.Client()))
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type Mode int
+
+const (
+	// ModeReplay replays the cassette, requests without recorded
+	// interaction fail.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests and records them, replacing the cassette.
+	ModeRecord
+	// ModeReplayOrRecord replays the cassette if it exists, and records it
+	// otherwise.
+	ModeReplayOrRecord
+)
+
+// Redacted replaces the values of redacted headers.
+const Redacted = "REDACTED"
+
+// DefaultRedactedHeaders carry the API keys of providers.
+var DefaultRedactedHeaders = []string{"Authorization", "X-Api-Key", "Api-Key", "X-Goog-Api-Key", "Cookie", "Set-Cookie"}
+
+// Cassette is the content of a fixture file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	// Body is the raw body, SSE streams are replayed as recorded.
+	Body Body `json:"body"`
+}
+
+// Body is an HTTP body. JSON object and array bodies are stored as JSON
+// values to keep fixtures readable, other bodies as strings.
+type Body string
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	trimmed := bytes.TrimSpace([]byte(b))
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return trimmed, nil
+	}
+	return json.Marshal(string(b))
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*b = Body(s)
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return err
+	}
+	*b = Body(buf.String())
+	return nil
+}
+
+type Options struct {
+	Mode Mode
+	// Transport sends requests when recording, defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+	// RedactedHeaders are the headers of requests and responses whose
+	// values are replaced by Redacted, defaults to DefaultRedactedHeaders.
+	RedactedHeaders []string
+	// Match reports whether a recorded request matches a request, defaults
+	// to MatchRequest.
+	Match func(r *http.Request, body []byte, recorded Request) bool
+}
+
+type Option func(o Options) Options
+
+func WithMode(mode Mode) Option {
+	return func(o Options) Options {
+		o.Mode = mode
+		return o
+	}
+}
+
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o Options) Options {
+		o.Transport = transport
+		return o
+	}
+}
+
+func WithRedactedHeaders(headers ...string) Option {
+	return func(o Options) Options {
+		o.RedactedHeaders = headers
+		return o
+	}
+}
+
+func WithMatch(match func(r *http.Request, body []byte, recorded Request) bool) Option {
+	return func(o Options) Options {
+		o.Match = match
+		return o
+	}
+}
+
+// Recorder is an http.RoundTripper recording or replaying a cassette.
+type Recorder struct {
+	path string
+	opts Options
+
+	mu        sync.Mutex
+	recording bool
+	cassette  Cassette
+	used      []bool
+}
+
+// New loads the cassette at path, unless recording.
+func New(path string, opts ...Option) (*Recorder, error) {
+	o := Options{
+		Transport:       http.DefaultTransport,
+		RedactedHeaders: DefaultRedactedHeaders,
+		Match:           MatchRequest,
+	}
+	for _, opt := range opts {
+		o = opt(o)
+	}
+	r := &Recorder{path: path, opts: o, recording: o.Mode == ModeRecord}
+	if r.recording {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && o.Mode == ModeReplayOrRecord {
+		r.recording = true
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("cassette: %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Client returns an HTTP client using the recorder.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Recording reports whether requests are sent and recorded.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if r.recording {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !r.opts.Match(req, body, interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+		resp := interaction.Response
+		header := resp.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+			StatusCode:    resp.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(string(resp.Body))),
+			ContentLength: int64(len(resp.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette: %s: no interaction recorded for %s %s", r.path, req.Method, req.URL)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.opts.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: r.redact(req.Header),
+			Body:   Body(body),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.redact(resp.Header),
+		},
+	}
+	// The body is recorded as the caller reads it, so streams are not
+	// delayed.
+	resp.Body = &recordBody{ReadCloser: resp.Body, done: func(data []byte) {
+		recorded.Response.Body = Body(data)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.cassette.Interactions = append(r.cassette.Interactions, recorded)
+	}}
+	return resp, nil
+}
+
+func (r *Recorder) redact(header http.Header) http.Header {
+	header = header.Clone()
+	for _, key := range r.opts.RedactedHeaders {
+		if _, ok := header[http.CanonicalHeaderKey(key)]; ok {
+			header.Set(key, Redacted)
+		}
+	}
+	return header
+}
+
+// Save writes the recorded cassette, it does nothing when replaying.
+// Interactions are saved once their response body is read or closed.
+func (r *Recorder) Save() error {
+	if !r.recording {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+// MatchRequest matches requests by method, URL and body. JSON bodies match
+// when they are equivalent.
+func MatchRequest(r *http.Request, body []byte, recorded Request) bool {
+	if r.Method != recorded.Method || r.URL.String() != recorded.URL {
+		return false
+	}
+	if string(body) == string(recorded.Body) {
+		return true
+	}
+	var got, want any
+	if json.Unmarshal(body, &got) != nil || json.Unmarshal([]byte(recorded.Body), &want) != nil {
+		return false
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+// recordBody collects the body read by the caller, done is called once
+// with the collected body at EOF or on Close.
+type recordBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	done func(data []byte)
+}
+
+func (b *recordBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordBody) finish() {
+	if b.done != nil {
+		b.done(b.buf.Bytes())
+		b.done = nil
+	}
+}
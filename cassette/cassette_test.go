@@ -0,0 +1,125 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const events = "data: {\"text\":\"Hello\"}\n\ndata: [DONE]\n\n"
+
+func newTestServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, events)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"text":"Hello"}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func post(t *testing.T, client *http.Client, url, body string) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "testdata", "cassette.json")
+
+	rec, err := New(path, WithMode(ModeRecord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := post(t, rec.Client(), srv.URL, `{"model":"m"}`); got != `{"text":"Hello"}` {
+		t.Errorf("got %q", got)
+	}
+	if got := post(t, rec.Client(), srv.URL, `{"model":"m","stream":true}`); got != events {
+		t.Errorf("got %q", got)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-secret") || !strings.Contains(string(data), Redacted) {
+		t.Errorf("API key not redacted:\n%s", data)
+	}
+
+	srv.Close()
+	rec, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Equivalent JSON bodies match.
+	if got := post(t, rec.Client(), srv.URL, `{"stream":true, "model":"m"}`); got != events {
+		t.Errorf("got %q, want %q", got, events)
+	}
+	if got := post(t, rec.Client(), srv.URL, `{"model":"m"}`); got != `{"text":"Hello"}` {
+		t.Errorf("got %q", got)
+	}
+
+	// Interactions are replayed once.
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"model":"m"}`))
+	if _, err := rec.Client().Do(req); err == nil || !strings.Contains(err.Error(), "no interaction recorded") {
+		t.Errorf("got error %v, want no interaction recorded", err)
+	}
+}
+
+func TestReplayOrRecord(t *testing.T) {
+	srv := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if _, err := New(path); err == nil {
+		t.Error("replaying a missing cassette succeeded")
+	}
+
+	rec, err := New(path, WithMode(ModeReplayOrRecord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Recording() {
+		t.Fatal("missing cassette not recorded")
+	}
+	post(t, rec.Client(), srv.URL, `{}`)
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err = New(path, WithMode(ModeReplayOrRecord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Recording() {
+		t.Error("existing cassette recorded again")
+	}
+}
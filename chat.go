@@ -1,16 +1,50 @@
 package aisuite
 
+import "encoding/json"
+
 type FunctionCall struct {
 	Name string
 	Args string
 }
 
 type ToolCall struct {
+	// Index is the position of the tool call in the message. Stream deltas of
+	// the same tool call share the same Index.
+	Index    int
 	ID       string
 	Tool     string
 	Function FunctionCall
 }
 
+// Tool describes a function the model may call. Parameters is a JSON schema
+// object describing the function arguments, e.g. a map[string]any or a
+// json.RawMessage.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  any
+}
+
+type ToolChoiceMode string
+
+const (
+	// ToolChoiceAuto lets the model decide whether to call tools.
+	ToolChoiceAuto ToolChoiceMode = "auto"
+	// ToolChoiceNone prevents the model from calling tools.
+	ToolChoiceNone ToolChoiceMode = "none"
+	// ToolChoiceRequired forces the model to call at least one tool.
+	ToolChoiceRequired ToolChoiceMode = "required"
+	// ToolChoiceFunction forces the model to call the tool named in ToolChoice.Name.
+	ToolChoiceFunction ToolChoiceMode = "function"
+)
+
+// ToolChoice controls how the model uses the tools of a request. The zero
+// value leaves the choice to the provider default.
+type ToolChoice struct {
+	Mode ToolChoiceMode
+	Name string
+}
+
 type FinishReason string
 
 const (
@@ -18,6 +52,7 @@ const (
 	FinishReasonStop          FinishReason = "stop"
 	FinishReasonMaxTokens     FinishReason = "max_tokens"
 	FinishReasonContentFilter FinishReason = "content_filter"
+	FinishReasonToolCalls     FinishReason = "tool_calls"
 	FinishReasonUnknown       FinishReason = "unknown"
 )
 
@@ -29,26 +64,175 @@ const (
 	RoleUser      Role = "user"
 	RoleSystem    Role = "system"
 	RoleAssistant Role = "assistant"
+	// RoleTool is the role of messages carrying the result of a tool call.
+	RoleTool Role = "tool"
 )
 
+type ContentPartType string
+
+const (
+	ContentPartTypeText     ContentPartType = "text"
+	ContentPartTypeImageURL ContentPartType = "image_url"
+	ContentPartTypeImage    ContentPartType = "image"
+	ContentPartTypeDocument ContentPartType = "document"
+	ContentPartTypeAudio    ContentPartType = "audio"
+)
+
+// ContentPart is a part of a multimodal message. Data holds the raw bytes of
+// inline images, documents and audio, providers encode it as they need.
+type ContentPart struct {
+	Type      ContentPartType
+	Text      string
+	URL       string
+	MediaType string
+	Data      []byte
+}
+
+func NewTextPart(text string) ContentPart {
+	return ContentPart{Type: ContentPartTypeText, Text: text}
+}
+
+func NewImageURLPart(url string) ContentPart {
+	return ContentPart{Type: ContentPartTypeImageURL, URL: url}
+}
+
+// NewImagePart returns an inline image part, e.g. NewImagePart("image/png", data).
+func NewImagePart(mediaType string, data []byte) ContentPart {
+	return ContentPart{Type: ContentPartTypeImage, MediaType: mediaType, Data: data}
+}
+
+// NewDocumentPart returns an inline document part, e.g. NewDocumentPart("application/pdf", data).
+func NewDocumentPart(mediaType string, data []byte) ContentPart {
+	return ContentPart{Type: ContentPartTypeDocument, MediaType: mediaType, Data: data}
+}
+
+// NewAudioPart returns an inline audio part, e.g. NewAudioPart("audio/wav", data).
+func NewAudioPart(mediaType string, data []byte) ContentPart {
+	return ContentPart{Type: ContentPartTypeAudio, MediaType: mediaType, Data: data}
+}
+
 type ChatCompletionMessage struct {
 	Role    Role
 	Content string
+	// MultiContent holds multimodal parts. When both are set, Content is
+	// sent as a text part before MultiContent.
+	MultiContent []ContentPart
+	// ToolCalls are the tools called by the assistant.
+	ToolCalls []ToolCall
+	// ToolCallID is the ID of the tool call answered by a RoleTool message.
+	ToolCallID string
+	// Refusal is the refusal message of the assistant, if any.
+	Refusal string
+}
+
+// Parts returns the content of the message as parts, with Content as a
+// leading text part.
+func (m ChatCompletionMessage) Parts() []ContentPart {
+	if m.Content == "" {
+		return m.MultiContent
+	}
+	parts := make([]ContentPart, 0, 1+len(m.MultiContent))
+	parts = append(parts, NewTextPart(m.Content))
+	return append(parts, m.MultiContent...)
+}
+
+type ResponseFormatType string
+
+const (
+	ResponseFormatTypeText       ResponseFormatType = "text"
+	ResponseFormatTypeJSONObject ResponseFormatType = "json_object"
+	ResponseFormatTypeJSONSchema ResponseFormatType = "json_schema"
+)
+
+// ResponseFormat asks the model to reply in a given format. Name,
+// Description, Schema and Strict only apply to ResponseFormatTypeJSONSchema,
+// Schema is a JSON schema like Tool.Parameters.
+type ResponseFormat struct {
+	Type        ResponseFormatType
+	Name        string
+	Description string
+	Schema      any
+	Strict      bool
+}
+
+// IsJSON reports whether the format asks for a JSON reply.
+func (f *ResponseFormat) IsJSON() bool {
+	return f != nil && (f.Type == ResponseFormatTypeJSONObject || f.Type == ResponseFormatTypeJSONSchema)
 }
 
 type ChatCompletionRequest struct {
-	Model     string
-	Messages  []ChatCompletionMessage
-	MaxTokens int
-	Stream    bool
+	Model      string
+	Messages   []ChatCompletionMessage
+	MaxTokens  int
+	Stream     bool
+	Tools      []Tool
+	ToolChoice ToolChoice
+	// ResponseFormat is the format of the reply, nil means plain text.
+	ResponseFormat *ResponseFormat
+
+	// Sampling parameters, nil or zero values use the provider defaults.
+	// Providers return an *UnsupportedParameterError for parameters they
+	// can't honor.
+	Temperature      *float64
+	TopP             *float64
+	TopK             *int
+	Stop             []string
+	Seed             *int
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+	LogitBias        map[string]int
+	// N is the number of choices to generate.
+	N int
+}
+
+// Ptr returns a pointer to v, it helps to set optional request parameters,
+// e.g. Temperature: aisuite.Ptr(0.2).
+func Ptr[T any](v T) *T {
+	return &v
 }
 
 type ChatCompletionChoice struct {
-	Message ChatCompletionMessage
+	Index        int
+	Message      ChatCompletionMessage
+	FinishReason FinishReason
+	// JSON is the JSON value of Message.Content when a JSON response format
+	// is requested. It is nil if the content is not valid JSON, e.g. when the
+	// reply is truncated.
+	JSON json.RawMessage
+}
+
+// Usage is the number of tokens used by a request.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	// CachedPromptTokens is the part of PromptTokens read from the provider's prompt cache.
+	CachedPromptTokens int
+	// CacheWritePromptTokens is the part of PromptTokens written to the provider's prompt cache.
+	CacheWritePromptTokens int
+	// ReasoningTokens is the part of CompletionTokens spent on reasoning.
+	ReasoningTokens int
+}
+
+// ResponseMetadata describes how a response was served, it is set by
+// clients dispatching requests rather than by providers.
+type ResponseMetadata struct {
+	// Model is the provider:model that served the response.
+	Model string
+	// Cost is the price of the response in USD, zero if unknown. It is set
+	// on the stream chunk reporting the usage.
+	Cost float64
+	// Cached is true when the response is replayed from a cache rather than
+	// generated by the provider.
+	Cached bool
 }
 
 type ChatCompletionResponse struct {
-	Choices []ChatCompletionChoice
+	ID       string
+	Model    string
+	Choices  []ChatCompletionChoice
+	Usage    Usage
+	Metadata ResponseMetadata
 }
 
 // ChatCompletionStreamResponse is the response from a chat completion stream.
@@ -62,6 +246,7 @@ type ChatCompletionStreamChoiceDelta struct {
 }
 
 type ChatCompletionStreamChoice struct {
+	Index        int
 	Delta        ChatCompletionStreamChoiceDelta
 	FinishReason FinishReason
 }
@@ -70,6 +255,10 @@ type ChatCompletionStreamResponse struct {
 	ID      string
 	Model   string
 	Choices []ChatCompletionStreamChoice
+	// Usage is only set on the chunk reporting the usage of the whole
+	// stream, usually the last one.
+	Usage    *Usage
+	Metadata ResponseMetadata
 }
 
 type ChatCompletionStream interface {
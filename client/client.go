@@ -3,7 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
-	"strings"
+	"net/http"
 
 	"github.com/cpunion/go-aisuite"
 	"github.com/cpunion/go-aisuite/providers"
@@ -14,8 +14,10 @@ import (
 	"github.com/cpunion/go-aisuite/providers/sambanova"
 )
 
-const (
-	ErrUnknownProvider = "unknown provider"
+var (
+	ErrUnknownProvider = providers.ErrUnknownProvider
+	ErrMissingAPIKey   = providers.ErrMissingAPIKey
+	ErrInvalidModel    = providers.ErrInvalidModel
 )
 
 type APIKey struct {
@@ -27,38 +29,116 @@ type APIKey struct {
 }
 
 type AdaptiveClient struct {
-	apiKey *APIKey
+	apiKey            *APIKey
+	httpClient        *http.Client
+	disableSDKRetries bool
 }
 
-func New(apiKey *APIKey) aisuite.Client {
+type Options struct {
+	Middlewares []aisuite.Middleware
+	// HTTPClient sends the requests of all providers.
+	HTTPClient *http.Client
+	// DisableSDKRetries turns off the retries of the provider SDKs, see
+	// providers.Options.
+	DisableSDKRetries bool
+}
+
+type Option func(o Options) Options
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o Options) Options {
+		o.HTTPClient = httpClient
+		return o
+	}
+}
+
+// WithDisableSDKRetries turns off the retries of the provider SDKs, to
+// leave them to a retry middleware.
+func WithDisableSDKRetries(disable bool) Option {
+	return func(o Options) Options {
+		o.DisableSDKRetries = disable
+		return o
+	}
+}
+
+// WithMiddleware installs middlewares around the client, the first one is
+// the outermost. They see the provider:model of requests.
+func WithMiddleware(middlewares ...aisuite.Middleware) Option {
+	return func(o Options) Options {
+		o.Middlewares = append(o.Middlewares, middlewares...)
+		return o
+	}
+}
+
+func New(apiKey *APIKey, opts ...Option) aisuite.Client {
 	if apiKey == nil {
 		apiKey = &APIKey{}
 	}
-	return AdaptiveClient{apiKey: apiKey}
+	o := Options{}
+	for _, opt := range opts {
+		o = opt(o)
+	}
+	return aisuite.Chain(o.Middlewares...)(AdaptiveClient{apiKey: apiKey, httpClient: o.HTTPClient, disableSDKRetries: o.DisableSDKRetries})
 }
 
 func (c AdaptiveClient) ChatCompletion(ctx context.Context, request aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
-	client, model := c.getClientAndModel(request.Model)
+	client, model, err := c.getClientAndModel(request.Model)
+	if err != nil {
+		return nil, err
+	}
 	newReq := request
 	newReq.Model = model
-	return client.ChatCompletion(ctx, newReq)
+	resp, err := client.ChatCompletion(ctx, newReq)
+	if err != nil {
+		return nil, err
+	}
+	resp.Metadata.Model = request.Model
+	return resp, nil
 }
 
 func (c AdaptiveClient) StreamChatCompletion(ctx context.Context, request aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
-	client, model := c.getClientAndModel(request.Model)
+	client, model, err := c.getClientAndModel(request.Model)
+	if err != nil {
+		return nil, err
+	}
 	newReq := request
 	newReq.Model = model
-	return client.StreamChatCompletion(ctx, newReq)
+	stream, err := client.StreamChatCompletion(ctx, newReq)
+	if err != nil {
+		return nil, err
+	}
+	return metadataStream{ChatCompletionStream: stream, model: request.Model}, nil
 }
 
-func (c AdaptiveClient) getClientAndModel(model string) (aisuite.Client, string) {
-	toks := strings.SplitN(model, ":", 2)
-	providerName := toks[0]
+// metadataStream sets the response metadata of the chunks.
+type metadataStream struct {
+	aisuite.ChatCompletionStream
+	model string
+}
+
+func (s metadataStream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
+	chunk, err := s.ChatCompletionStream.Recv()
+	if err == nil {
+		chunk.Metadata.Model = s.model
+	}
+	return chunk, err
+}
+
+// ParseModel splits a "provider:model" string, see providers.ParseModel.
+func ParseModel(model string) (providerName, modelName string, err error) {
+	return providers.ParseModel(model)
+}
+
+func (c AdaptiveClient) getClientAndModel(model string) (aisuite.Client, string, error) {
+	providerName, modelName, err := ParseModel(model)
+	if err != nil {
+		return nil, "", err
+	}
 	provider, ok := providers.GetProvider(providerName)
 	if !ok {
-		panic(fmt.Sprintf("%s: %s", ErrUnknownProvider, providerName))
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownProvider, providerName)
 	}
-	opts := providers.Options{}
+	opts := providers.Options{HTTPClient: c.httpClient, DisableSDKRetries: c.disableSDKRetries}
 	switch providerName {
 	case openai.Name:
 		opts.Token = c.apiKey.OpenAI
@@ -74,5 +154,9 @@ func (c AdaptiveClient) getClientAndModel(model string) (aisuite.Client, string)
 		opts.Token = c.apiKey.Groq
 		opts.BaseURL = "https://api.groq.com/openai/v1/"
 	}
-	return provider.NewClient(opts), toks[1]
+	client, err := provider.NewClient(opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, modelName, nil
 }
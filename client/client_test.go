@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -56,6 +57,15 @@ func generateTestCases() []testCase {
 	return cases
 }
 
+// requireAPIKey skips live tests of providers without API key.
+func requireAPIKey(t *testing.T, model string) {
+	provider, _, _ := ParseModel(model)
+	envVar := strings.ToUpper(provider) + "_API_KEY"
+	if os.Getenv(envVar) == "" {
+		t.Skipf("%s not set", envVar)
+	}
+}
+
 func withTimeout(t *testing.T, timeout time.Duration, fn func(ctx context.Context)) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -79,6 +89,7 @@ func TestChatCompletion(t *testing.T) {
 	models := testModels
 	for _, model := range models {
 		t.Run(model, func(t *testing.T) {
+			requireAPIKey(t, model)
 			wd, _ := os.Getwd()
 			t.Logf("Working directory: %s", wd)
 			withTimeout(t, 10*time.Second, func(ctx context.Context) {
@@ -110,6 +121,7 @@ func TestStreamChatCompletion(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
+			requireAPIKey(t, tc.model)
 			withTimeout(t, 10*time.Second, func(ctx context.Context) {
 				stream, err := client.StreamChatCompletion(ctx, aisuite.ChatCompletionRequest{
 					Model: tc.model,
@@ -154,3 +166,71 @@ func TestStreamChatCompletion(t *testing.T) {
 		})
 	}
 }
+
+func TestParseModel(t *testing.T) {
+	provider, model, err := ParseModel("openai:gpt-4o-mini")
+	if err != nil || provider != "openai" || model != "gpt-4o-mini" {
+		t.Errorf("ParseModel() = %q, %q, %v", provider, model, err)
+	}
+	for _, model := range []string{"", "gpt-4o-mini", "openai:", ":gpt-4o-mini"} {
+		if _, _, err := ParseModel(model); !errors.Is(err, ErrInvalidModel) {
+			t.Errorf("ParseModel(%q) error = %v, want ErrInvalidModel", model, err)
+		}
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	client := New(nil)
+	tests := []struct {
+		model string
+		want  error
+	}{
+		{"gpt-4o-mini", ErrInvalidModel},
+		{"unknown:model", ErrUnknownProvider},
+		{"openai:gpt-4o-mini", ErrMissingAPIKey},
+	}
+	for _, tt := range tests {
+		req := aisuite.ChatCompletionRequest{Model: tt.model}
+		if _, err := client.ChatCompletion(context.Background(), req); !errors.Is(err, tt.want) {
+			t.Errorf("ChatCompletion(%q) error = %v, want %v", tt.model, err, tt.want)
+		}
+		if _, err := client.StreamChatCompletion(context.Background(), req); !errors.Is(err, tt.want) {
+			t.Errorf("StreamChatCompletion(%q) error = %v, want %v", tt.model, err, tt.want)
+		}
+	}
+}
+
+func TestWithMiddleware(t *testing.T) {
+	var models []string
+	record := func(next aisuite.Client) aisuite.Client {
+		return aisuite.ClientFuncs{
+			Next: next,
+			ChatCompletionFunc: func(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+				models = append(models, req.Model)
+				return next.ChatCompletion(ctx, req)
+			},
+		}
+	}
+	cached := func(next aisuite.Client) aisuite.Client {
+		return aisuite.ClientFuncs{
+			Next: next,
+			ChatCompletionFunc: func(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+				return &aisuite.ChatCompletionResponse{Model: req.Model}, nil
+			},
+		}
+	}
+	client := New(nil, WithMiddleware(record), WithMiddleware(cached))
+	resp, err := client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{Model: "openai:gpt-4o-mini"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "openai:gpt-4o-mini" || len(models) != 1 || models[0] != "openai:gpt-4o-mini" {
+		t.Errorf("got response %+v, models %v", resp, models)
+	}
+
+	client = New(nil, WithMiddleware(record))
+	if _, err = client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{Model: "unknown:model"}); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("got error %v, want ErrUnknownProvider", err)
+	}
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cpunion/go-aisuite"
+)
+
+type FallbackOptions struct {
+	// FallbackIf decides whether the next model is tried after an error,
+	// defaults to aisuite.IsRetryable.
+	FallbackIf func(err error) bool
+}
+
+type FallbackOption func(o FallbackOptions) FallbackOptions
+
+func WithFallbackIf(fallbackIf func(err error) bool) FallbackOption {
+	return func(o FallbackOptions) FallbackOptions {
+		o.FallbackIf = fallbackIf
+		return o
+	}
+}
+
+// FallbackClient sends requests to a chain of models, falling over to the
+// next model when a model fails with a retryable error.
+type FallbackClient struct {
+	client aisuite.Client
+	models []string
+	opts   FallbackOptions
+}
+
+// NewFallback returns a client trying the provider:model strings of models
+// in order with client, the model of requests is ignored. The model serving
+// a response is reported in its Metadata.Model.
+//
+// When every model fails, the joined errors of all models are returned. When
+// an error that doesn't fall over stops the chain, that error is returned
+// with the errors of the models tried before.
+func NewFallback(client aisuite.Client, models []string, opts ...FallbackOption) *FallbackClient {
+	o := FallbackOptions{FallbackIf: aisuite.IsRetryable}
+	for _, opt := range opts {
+		o = opt(o)
+	}
+	return &FallbackClient{client: client, models: models, opts: o}
+}
+
+// FallbackMiddleware returns a middleware sending requests to a chain of
+// models, see NewFallback.
+func FallbackMiddleware(models []string, opts ...FallbackOption) aisuite.Middleware {
+	return func(next aisuite.Client) aisuite.Client {
+		return NewFallback(next, models, opts...)
+	}
+}
+
+func (c *FallbackClient) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	var errs []error
+	for _, model := range c.models {
+		req.Model = model
+		resp, err := c.client.ChatCompletion(ctx, req)
+		if err == nil {
+			resp.Metadata.Model = model
+			return resp, nil
+		}
+		if errs = append(errs, fmt.Errorf("%s: %w", model, err)); !c.fallback(ctx, err) {
+			break
+		}
+	}
+	return nil, c.join(errs)
+}
+
+// StreamChatCompletion falls over to the next model when opening the stream
+// or receiving its first chunk fails. Once a chunk is delivered, errors are
+// returned to the caller.
+func (c *FallbackClient) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
+	s := &fallbackStream{client: c, ctx: ctx, req: req}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *FallbackClient) fallback(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && c.opts.FallbackIf(err)
+}
+
+// join returns the error of a chain that failed with errs, one per model
+// tried. When an error stopped the chain before its last model, that error is
+// returned with the earlier ones attached.
+func (c *FallbackClient) join(errs []error) error {
+	switch {
+	case len(errs) == 0:
+		return fmt.Errorf("%w: no fallback models", ErrInvalidModel)
+	case len(errs) == 1:
+		return errs[0]
+	case len(errs) == len(c.models):
+		return fmt.Errorf("all models failed: %w", errors.Join(errs...))
+	}
+	last := len(errs) - 1
+	return fmt.Errorf("%w\nafter: %w", errs[last], errors.Join(errs[:last]...))
+}
+
+type fallbackStream struct {
+	client    *FallbackClient
+	ctx       context.Context
+	req       aisuite.ChatCompletionRequest
+	stream    aisuite.ChatCompletionStream
+	next      int
+	model     string
+	errs      []error
+	delivered bool
+	// err is returned by Recv once no replacement stream could be opened.
+	err error
+}
+
+// open opens the stream of the next model that doesn't fail.
+func (s *fallbackStream) open() error {
+	for s.next < len(s.client.models) {
+		s.model = s.client.models[s.next]
+		s.next++
+		s.req.Model = s.model
+		stream, err := s.client.client.StreamChatCompletion(s.ctx, s.req)
+		if err == nil {
+			s.stream = stream
+			return nil
+		}
+		if !s.failed(err) {
+			break
+		}
+	}
+	return s.client.join(s.errs)
+}
+
+// failed records the error of the current model and reports whether the
+// next model is tried.
+func (s *fallbackStream) failed(err error) bool {
+	s.errs = append(s.errs, fmt.Errorf("%s: %w", s.model, err))
+	return s.client.fallback(s.ctx, err)
+}
+
+func (s *fallbackStream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
+	if s.stream == nil {
+		return aisuite.ChatCompletionStreamResponse{}, s.err
+	}
+	for {
+		chunk, err := s.stream.Recv()
+		if err == nil || s.delivered || err == io.EOF {
+			s.delivered = true
+			chunk.Metadata.Model = s.model
+			return chunk, err
+		}
+		if !s.failed(err) || s.next == len(s.client.models) {
+			return chunk, s.client.join(s.errs)
+		}
+		s.stream.Close()
+		s.stream = nil
+		if s.err = s.open(); s.err != nil {
+			return chunk, s.err
+		}
+	}
+}
+
+func (s *fallbackStream) Close() error {
+	if s.stream == nil {
+		return nil
+	}
+	return s.stream.Close()
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers/mock"
+)
+
+// models returns the models of the requests fake received.
+func models(fake *mock.Client) []string {
+	var models []string
+	for _, req := range fake.Requests() {
+		models = append(models, req.Model)
+	}
+	return models
+}
+
+var (
+	overloaded = &aisuite.Error{Category: aisuite.ErrorCategoryOverloaded, StatusCode: 529}
+	badRequest = &aisuite.Error{Category: aisuite.ErrorCategoryInvalidRequest, StatusCode: 400}
+)
+
+var fallbackModels = []string{"openai:gpt-4o-mini", "anthropic:claude-3-5-haiku-20241022", "groq:llama-3.1-8b-instant"}
+
+func TestFallbackChatCompletion(t *testing.T) {
+	fake := mock.NewClient(mock.Fail(overloaded), mock.Reply("Hello"))
+	client := NewFallback(fake, fallbackModels)
+	resp, err := client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{Model: "ignored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Metadata.Model != "anthropic:claude-3-5-haiku-20241022" {
+		t.Errorf("served by %q", resp.Metadata.Model)
+	}
+	if calls := models(fake); len(calls) != 2 {
+		t.Errorf("got calls %v", calls)
+	}
+
+	fake = mock.NewClient(mock.Fail(badRequest))
+	client = NewFallback(fake, fallbackModels)
+	if _, err = client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{}); !errors.Is(err, badRequest) {
+		t.Errorf("got error %v, want the bad request error", err)
+	}
+	if calls := models(fake); len(calls) != 1 {
+		t.Errorf("got calls %v, want no fallback", calls)
+	}
+
+	fake = mock.NewClient(mock.Fail(overloaded), mock.Fail(overloaded), mock.Fail(badRequest))
+	client = NewFallback(fake, fallbackModels)
+	_, err = client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{})
+	if !errors.Is(err, overloaded) || !errors.Is(err, badRequest) || !strings.HasPrefix(err.Error(), "all models failed") {
+		t.Errorf("got error %v, want the errors of all models", err)
+	}
+
+	fake = mock.NewClient(mock.Fail(overloaded), mock.Fail(badRequest))
+	client = NewFallback(fake, fallbackModels)
+	_, err = client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{})
+	checkStopped(t, err)
+}
+
+// checkStopped checks err is the bad request error of the second model
+// stopping the chain, with the error of the first attached.
+func checkStopped(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, badRequest) || !errors.Is(err, overloaded) {
+		t.Errorf("got error %v, want the errors of the tried models", err)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "anthropic:claude-3-5-haiku-20241022: ") || strings.Contains(msg, "all models failed") {
+		t.Errorf("got error %q, want the stopping error first", msg)
+	}
+}
+
+func TestFallbackStreamChatCompletion(t *testing.T) {
+	fake := mock.NewClient(mock.Fail(overloaded), mock.FailRecv(overloaded), mock.Reply("Hello"))
+	client := NewFallback(fake, fallbackModels)
+	stream, err := client.StreamChatCompletion(context.Background(), aisuite.ChatCompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	resp, err := aisuite.AccumulateStream(stream, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Metadata.Model != "groq:llama-3.1-8b-instant" || resp.Choices[0].Message.Content != "Hello" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	fake = mock.NewClient(mock.FailRecv(overloaded), mock.FailRecv(badRequest))
+	client = NewFallback(fake, fallbackModels)
+	stream, err = client.StreamChatCompletion(context.Background(), aisuite.ChatCompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	_, err = stream.Recv()
+	checkStopped(t, err)
+	if calls := models(fake); len(calls) != 2 {
+		t.Errorf("got calls %v", calls)
+	}
+}
+
+func TestFallbackStreamReopenError(t *testing.T) {
+	fake := mock.NewClient(mock.FailRecv(overloaded), mock.Fail(badRequest))
+	client := NewFallback(fake, fallbackModels)
+	stream, err := client.StreamChatCompletion(context.Background(), aisuite.ChatCompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = stream.Recv(); !errors.Is(err, badRequest) {
+			t.Errorf("got error %v, want the bad request error", err)
+		}
+	}
+	stream.Close()
+	if fake.Closed() != 1 {
+		t.Errorf("got %d streams closed, want 1", fake.Closed())
+	}
+}
@@ -0,0 +1,220 @@
+// Package cost computes the price of chat completions and tracks spend.
+package cost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cpunion/go-aisuite"
+)
+
+// ErrBudgetExceeded is returned for requests sent once a budget is spent.
+var ErrBudgetExceeded = errors.New("cost: budget exceeded")
+
+type Options struct {
+	// Prices default to DefaultPrices.
+	Prices Prices
+	// Budget is the total spend in USD after which requests are rejected,
+	// zero is unlimited.
+	Budget float64
+	// TagBudgets are the budgets of tags.
+	TagBudgets map[string]float64
+}
+
+type Option func(o Options) Options
+
+// WithPrices overrides the default prices, see Prices.Merge.
+func WithPrices(prices Prices) Option {
+	return func(o Options) Options {
+		o.Prices = o.Prices.Merge(prices)
+		return o
+	}
+}
+
+func WithBudget(budget float64) Option {
+	return func(o Options) Options {
+		o.Budget = budget
+		return o
+	}
+}
+
+func WithTagBudget(tag string, budget float64) Option {
+	return func(o Options) Options {
+		if o.TagBudgets == nil {
+			o.TagBudgets = make(map[string]float64)
+		}
+		o.TagBudgets[tag] = budget
+		return o
+	}
+}
+
+type tagsKey struct{}
+
+// WithTags returns a context attributing the spend of requests to tags,
+// e.g. "tenant:acme" or "feature:search".
+func WithTags(ctx context.Context, tags ...string) context.Context {
+	tags = append(Tags(ctx), tags...)
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// Tags returns the tags of ctx.
+func Tags(ctx context.Context) []string {
+	tags, _ := ctx.Value(tagsKey{}).([]string)
+	return tags[:len(tags):len(tags)]
+}
+
+// Tracker prices usage and tracks the spend, in total and by tag.
+type Tracker struct {
+	opts Options
+
+	mu    sync.Mutex
+	total float64
+	tags  map[string]float64
+}
+
+func NewTracker(opts ...Option) *Tracker {
+	o := Options{Prices: DefaultPrices}
+	for _, opt := range opts {
+		o = opt(o)
+	}
+	return &Tracker{opts: o, tags: make(map[string]float64)}
+}
+
+// Total returns the total spend in USD.
+func (t *Tracker) Total() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// Spend returns the spend of a tag in USD.
+func (t *Tracker) Spend(tag string) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tags[tag]
+}
+
+// Cost returns the price of usage of a provider:model, false if the model
+// has no price.
+func (t *Tracker) Cost(model string, usage aisuite.Usage) (float64, bool) {
+	price, ok := t.opts.Prices.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return price.Cost(usage), true
+}
+
+// Check returns ErrBudgetExceeded if the total budget or the budget of a
+// tag is spent.
+func (t *Tracker) Check(tags []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.opts.Budget > 0 && t.total >= t.opts.Budget {
+		return ErrBudgetExceeded
+	}
+	for _, tag := range tags {
+		if budget, ok := t.opts.TagBudgets[tag]; ok && t.tags[tag] >= budget {
+			return fmt.Errorf("%w: %s", ErrBudgetExceeded, tag)
+		}
+	}
+	return nil
+}
+
+// Record adds the cost of usage of a provider:model to the spend of tags
+// and returns it, models without price cost nothing.
+func (t *Tracker) Record(model string, usage aisuite.Usage, tags []string) float64 {
+	cost, ok := t.Cost(model, usage)
+	if !ok {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total += cost
+	for _, tag := range tags {
+		t.tags[tag] += cost
+	}
+	return cost
+}
+
+// Client sets the cost of the responses of a client in their Metadata.Cost
+// and records it in a tracker, attributed to the tags of the request
+// context. Responses replayed from a cache cost nothing.
+//
+// Budgets are checked before sending requests, requests in flight when a
+// budget is spent may exceed it.
+type Client struct {
+	client  aisuite.Client
+	tracker *Tracker
+}
+
+func New(client aisuite.Client, tracker *Tracker) *Client {
+	return &Client{client: client, tracker: tracker}
+}
+
+// Middleware returns a middleware recording costs in tracker, see New.
+func Middleware(tracker *Tracker) aisuite.Middleware {
+	return func(next aisuite.Client) aisuite.Client {
+		return New(next, tracker)
+	}
+}
+
+func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	tags := Tags(ctx)
+	if err := c.tracker.Check(tags); err != nil {
+		return nil, err
+	}
+	resp, err := c.client.ChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Metadata.Cached {
+		resp.Metadata.Cost = c.tracker.Record(servedModel(req, resp.Metadata), resp.Usage, tags)
+	}
+	return resp, nil
+}
+
+func (c *Client) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
+	tags := Tags(ctx)
+	if err := c.tracker.Check(tags); err != nil {
+		return nil, err
+	}
+	stream, err := c.client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &costStream{ChatCompletionStream: stream, client: c, req: req, tags: tags}, nil
+}
+
+// servedModel returns the model serving a response, it differs from the
+// requested one when falling back.
+func servedModel(req aisuite.ChatCompletionRequest, metadata aisuite.ResponseMetadata) string {
+	if metadata.Model != "" {
+		return metadata.Model
+	}
+	return req.Model
+}
+
+type costStream struct {
+	aisuite.ChatCompletionStream
+	client *Client
+	req    aisuite.ChatCompletionRequest
+	tags   []string
+	model  string
+}
+
+func (s *costStream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
+	chunk, err := s.ChatCompletionStream.Recv()
+	if err != nil {
+		return chunk, err
+	}
+	if chunk.Metadata.Model != "" {
+		s.model = chunk.Metadata.Model
+	}
+	if chunk.Usage != nil && !chunk.Metadata.Cached {
+		model := servedModel(s.req, aisuite.ResponseMetadata{Model: s.model})
+		chunk.Metadata.Cost = s.client.tracker.Record(model, *chunk.Usage, s.tags)
+	}
+	return chunk, nil
+}
@@ -0,0 +1,118 @@
+package cost
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers/mock"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
+
+func TestPriceCost(t *testing.T) {
+	price := Price{Input: 2.50, CachedInput: 1.25, Output: 10}
+	usage := aisuite.Usage{PromptTokens: 2000, CachedPromptTokens: 1000, CompletionTokens: 500, ReasoningTokens: 100}
+	// 1000*2.50 + 1000*1.25 + 500*10
+	if got := price.Cost(usage); !near(got, 0.00875) {
+		t.Errorf("got cost %v, want 0.00875", got)
+	}
+	price = Price{Input: 1, Output: 2, Reasoning: 4}
+	if got := price.Cost(usage); !near(got, (2000*1+400*2+100*4)/1e6) {
+		t.Errorf("got cost %v", got)
+	}
+	// Anthropic charges prompt cache writes above the input price.
+	price = Price{Input: 3, CachedInput: 0.30, CacheWrite: 3.75, Output: 15}
+	usage = aisuite.Usage{PromptTokens: 1300, CachedPromptTokens: 1000, CacheWritePromptTokens: 200, CompletionTokens: 100}
+	if got := price.Cost(usage); !near(got, (100*3+1000*0.30+200*3.75+100*15)/1e6) {
+		t.Errorf("got cost %v", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := map[string]string{
+		"openai:gpt-4o":                       "openai:gpt-4o",
+		"openai:gpt-4o-2024-08-06":            "openai:gpt-4o",
+		"openai:gpt-4o-mini-2024-07-18":       "openai:gpt-4o-mini",
+		"openai:o1-mini":                      "openai:o1-mini",
+		"anthropic:claude-3-5-haiku-20241022": "anthropic:claude-3-5-haiku",
+		"openai:gpt-4oo":                      "",
+		"mock:scripted":                       "",
+	}
+	for model, key := range tests {
+		price, ok := DefaultPrices.Lookup(model)
+		if ok != (key != "") || ok && price != DefaultPrices[key] {
+			t.Errorf("Lookup(%q) = %v, %v, want %q", model, price, ok, key)
+		}
+	}
+}
+
+// reply returns a step replying with usage.
+func reply(usage aisuite.Usage) mock.Step {
+	step := mock.Reply("Hello!")
+	step.Response.Usage = usage
+	return step
+}
+
+func TestClient(t *testing.T) {
+	tracker := NewTracker(
+		WithPrices(Prices{"openai:gpt-4o-mini": {Input: 1, Output: 2}}),
+		WithTagBudget("tenant:acme", 0.002))
+	usage := aisuite.Usage{PromptTokens: 1000, CompletionTokens: 1000}
+	served := aisuite.ResponseMetadata{Model: "anthropic:claude-3-5-haiku-20241022"}
+	client := New(mock.NewClient(
+		reply(usage),
+		mock.Step{Chunks: []aisuite.ChatCompletionStreamResponse{{Metadata: served}, {Usage: &usage, Metadata: served}}},
+		reply(usage),
+		reply(usage),
+	), tracker)
+	ctx := WithTags(context.Background(), "tenant:acme", "feature:search")
+
+	resp, err := client.ChatCompletion(ctx, aisuite.ChatCompletionRequest{Model: "openai:gpt-4o-mini"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(resp.Metadata.Cost, 0.003) {
+		t.Errorf("got cost %v, want 0.003", resp.Metadata.Cost)
+	}
+
+	// Streams are priced with the model serving them.
+	stream, err := client.StreamChatCompletion(WithTags(context.Background(), "feature:search"), aisuite.ChatCompletionRequest{Model: "openai:gpt-4o-mini"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err := aisuite.AccumulateStream(stream, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(acc.Metadata.Cost, 0.0048) {
+		t.Errorf("got stream cost %v, want 0.0048", acc.Metadata.Cost)
+	}
+
+	if !near(tracker.Total(), 0.0078) || !near(tracker.Spend("tenant:acme"), 0.003) || !near(tracker.Spend("feature:search"), 0.0078) {
+		t.Errorf("got total %v, acme %v, search %v", tracker.Total(), tracker.Spend("tenant:acme"), tracker.Spend("feature:search"))
+	}
+
+	if _, err = client.ChatCompletion(ctx, aisuite.ChatCompletionRequest{Model: "openai:gpt-4o-mini"}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("got error %v, want ErrBudgetExceeded", err)
+	}
+	if _, err = client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{Model: "openai:gpt-4o-mini"}); err != nil {
+		t.Errorf("untagged request rejected: %v", err)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tracker := NewTracker(WithBudget(0.001))
+	client := New(mock.NewClient(reply(aisuite.Usage{PromptTokens: 10000}), reply(aisuite.Usage{})), tracker)
+	req := aisuite.ChatCompletionRequest{Model: "openai:gpt-4o-mini"}
+	if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.StreamChatCompletion(context.Background(), req); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("got error %v, want ErrBudgetExceeded", err)
+	}
+}
@@ -0,0 +1,102 @@
+package cost
+
+import (
+	"strings"
+
+	"github.com/cpunion/go-aisuite"
+)
+
+// Price is the price of a model in USD per million tokens.
+type Price struct {
+	Input float64
+	// CachedInput is the price of prompt tokens read from the cache,
+	// defaults to Input.
+	CachedInput float64
+	// CacheWrite is the price of prompt tokens written to the cache,
+	// defaults to Input.
+	CacheWrite float64
+	Output     float64
+	// Reasoning is the price of reasoning tokens, defaults to Output.
+	Reasoning float64
+}
+
+// Cost returns the price of usage in USD.
+func (p Price) Cost(usage aisuite.Usage) float64 {
+	cachedInput, cacheWrite, reasoning := p.CachedInput, p.CacheWrite, p.Reasoning
+	if cachedInput == 0 {
+		cachedInput = p.Input
+	}
+	if cacheWrite == 0 {
+		cacheWrite = p.Input
+	}
+	if reasoning == 0 {
+		reasoning = p.Output
+	}
+	cost := float64(usage.PromptTokens-usage.CachedPromptTokens-usage.CacheWritePromptTokens)*p.Input +
+		float64(usage.CachedPromptTokens)*cachedInput +
+		float64(usage.CacheWritePromptTokens)*cacheWrite +
+		float64(usage.CompletionTokens-usage.ReasoningTokens)*p.Output +
+		float64(usage.ReasoningTokens)*reasoning
+	return cost / 1e6
+}
+
+// Prices are model prices keyed by provider:model. A key also prices the
+// versions of the model, e.g. "openai:gpt-4o" prices
+// "openai:gpt-4o-2024-08-06".
+type Prices map[string]Price
+
+// Lookup returns the price of a provider:model, the longest matching key
+// wins.
+func (p Prices) Lookup(model string) (Price, bool) {
+	if price, ok := p[model]; ok {
+		return price, true
+	}
+	var price Price
+	var found string
+	for key, pr := range p {
+		if strings.HasPrefix(model, key+"-") && len(key) > len(found) {
+			price, found = pr, key
+		}
+	}
+	return price, found != ""
+}
+
+// Merge returns the prices of p overridden by overrides.
+func (p Prices) Merge(overrides Prices) Prices {
+	merged := make(Prices, len(p)+len(overrides))
+	for key, price := range p {
+		merged[key] = price
+	}
+	for key, price := range overrides {
+		merged[key] = price
+	}
+	return merged
+}
+
+// DefaultPrices are the list prices of common models, check them against
+// the providers' pricing pages before relying on them.
+var DefaultPrices = Prices{
+	"openai:gpt-4o":        {Input: 2.50, CachedInput: 1.25, Output: 10},
+	"openai:gpt-4o-mini":   {Input: 0.15, CachedInput: 0.075, Output: 0.60},
+	"openai:o1":            {Input: 15, CachedInput: 7.50, Output: 60},
+	"openai:o1-preview":    {Input: 15, CachedInput: 7.50, Output: 60},
+	"openai:o1-mini":       {Input: 3, CachedInput: 1.50, Output: 12},
+	"openai:gpt-4-turbo":   {Input: 10, Output: 30},
+	"openai:gpt-3.5-turbo": {Input: 0.50, Output: 1.50},
+
+	"anthropic:claude-3-5-sonnet": {Input: 3, CachedInput: 0.30, CacheWrite: 3.75, Output: 15},
+	"anthropic:claude-3-5-haiku":  {Input: 0.80, CachedInput: 0.08, CacheWrite: 1, Output: 4},
+	"anthropic:claude-3-opus":     {Input: 15, CachedInput: 1.50, CacheWrite: 18.75, Output: 75},
+	"anthropic:claude-3-haiku":    {Input: 0.25, CachedInput: 0.03, CacheWrite: 0.30, Output: 1.25},
+
+	"gemini:gemini-1.5-flash": {Input: 0.075, CachedInput: 0.01875, Output: 0.30},
+	"gemini:gemini-1.5-pro":   {Input: 1.25, CachedInput: 0.3125, Output: 5},
+
+	"groq:llama-3.1-8b-instant":    {Input: 0.05, Output: 0.08},
+	"groq:llama-3.1-70b-versatile": {Input: 0.59, Output: 0.79},
+	"groq:mixtral-8x7b-32768":      {Input: 0.24, Output: 0.24},
+
+	"sambanova:Meta-Llama-3.1-8B-Instruct":  {Input: 0.10, Output: 0.20},
+	"sambanova:Meta-Llama-3.1-70B-Instruct": {Input: 0.60, Output: 1.20},
+	"sambanova:Meta-Llama-3.2-1B-Instruct":  {Input: 0.04, Output: 0.08},
+}
@@ -0,0 +1,145 @@
+package aisuite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ErrUnsupportedContentPart is returned when a provider can't send a content part type.
+var ErrUnsupportedContentPart = errors.New("unsupported content part")
+
+// UnsupportedParameterError is returned when a provider can't honor a request
+// parameter.
+type UnsupportedParameterError struct {
+	Provider  string
+	Parameter string
+}
+
+func (e *UnsupportedParameterError) Error() string {
+	return fmt.Sprintf("%s: unsupported parameter %s", e.Provider, e.Parameter)
+}
+
+type ErrorCategory string
+
+const (
+	ErrorCategoryUnknown               ErrorCategory = "unknown"
+	ErrorCategoryAuth                  ErrorCategory = "auth"
+	ErrorCategoryRateLimit             ErrorCategory = "rate_limit"
+	ErrorCategoryQuota                 ErrorCategory = "quota"
+	ErrorCategoryContextLengthExceeded ErrorCategory = "context_length_exceeded"
+	ErrorCategoryContentFiltered       ErrorCategory = "content_filtered"
+	ErrorCategoryInvalidRequest        ErrorCategory = "invalid_request"
+	ErrorCategoryOverloaded            ErrorCategory = "overloaded"
+	ErrorCategoryServer                ErrorCategory = "server"
+	ErrorCategoryTimeout               ErrorCategory = "timeout"
+)
+
+// Error is a provider error normalized across providers, use errors.As to
+// inspect it. Err is the original error of the provider SDK.
+type Error struct {
+	Category   ErrorCategory
+	StatusCode int
+	Provider   string
+	RequestID  string
+	// RetryAfter is the delay asked by the provider before retrying, zero if
+	// not given.
+	RetryAfter time.Duration
+	Message    string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Provider, e.Category)
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// StatusCategory returns the error category of an HTTP status code,
+// providers refine it with their error codes.
+func StatusCategory(status int) ErrorCategory {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorCategoryAuth
+	case status == http.StatusTooManyRequests:
+		return ErrorCategoryRateLimit
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrorCategoryTimeout
+	case status == http.StatusServiceUnavailable || status == 529:
+		return ErrorCategoryOverloaded
+	case status >= 500:
+		return ErrorCategoryServer
+	case status >= 400:
+		return ErrorCategoryInvalidRequest
+	}
+	return ErrorCategoryUnknown
+}
+
+// ParseRetryAfter returns the delay of the retry-after-ms or Retry-After
+// headers, Retry-After may be a number of seconds or an HTTP date.
+func ParseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// IsRetryable reports whether a request failing with err may succeed when
+// sent again: rate limits, overloaded or failing servers, timeouts and
+// dropped connections.
+func IsRetryable(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		switch e.Category {
+		case ErrorCategoryRateLimit, ErrorCategoryOverloaded, ErrorCategoryServer, ErrorCategoryTimeout:
+			return true
+		}
+		return false
+	}
+	return IsNetworkError(err)
+}
+
+// IsNetworkError reports whether err is a connection failure before a
+// response was received: a reset or refused connection, a broken pipe, a
+// truncated response or a network timeout. Other transport errors such as
+// TLS or DNS resolution failures won't go away when retrying, and context
+// errors are the caller's decision.
+func IsNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
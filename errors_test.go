@@ -0,0 +1,110 @@
+package aisuite
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStatusCategory(t *testing.T) {
+	tests := map[int]ErrorCategory{
+		400: ErrorCategoryInvalidRequest,
+		401: ErrorCategoryAuth,
+		403: ErrorCategoryAuth,
+		408: ErrorCategoryTimeout,
+		429: ErrorCategoryRateLimit,
+		500: ErrorCategoryServer,
+		503: ErrorCategoryOverloaded,
+		504: ErrorCategoryTimeout,
+		529: ErrorCategoryOverloaded,
+		200: ErrorCategoryUnknown,
+	}
+	for status, want := range tests {
+		if got := StatusCategory(status); got != want {
+			t.Errorf("StatusCategory(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header http.Header
+		want   time.Duration
+	}{
+		{http.Header{}, 0},
+		{http.Header{"Retry-After": {"2"}}, 2 * time.Second},
+		{http.Header{"Retry-After": {"0.5"}}, 500 * time.Millisecond},
+		{http.Header{"Retry-After": {"Fri, 01 Nov 2024 12:00:30 GMT"}}, 30 * time.Second},
+		{http.Header{"Retry-After": {"Fri, 01 Nov 2024 11:00:00 GMT"}}, 0},
+		{http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"1"}}, 250 * time.Millisecond},
+		{http.Header{"Retry-After": {"soon"}}, 0},
+	}
+	for _, tt := range tests {
+		if got := ParseRetryAfter(tt.header, now); got != tt.want {
+			t.Errorf("ParseRetryAfter(%v) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestErrorAs(t *testing.T) {
+	cause := errors.New("sdk error")
+	err := fmt.Errorf("request failed: %w", &Error{
+		Category:   ErrorCategoryRateLimit,
+		StatusCode: 429,
+		Provider:   "openai",
+		Message:    "Rate limit reached",
+		Err:        cause,
+	})
+	var e *Error
+	if !errors.As(err, &e) || e.Category != ErrorCategoryRateLimit {
+		t.Fatalf("errors.As(%v) failed", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("error does not unwrap to its cause")
+	}
+	if got, want := e.Error(), "openai: rate_limit (status 429): Rate limit reached"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&Error{Category: ErrorCategoryRateLimit}, true},
+		{&Error{Category: ErrorCategoryServer}, true},
+		{&Error{Category: ErrorCategoryQuota}, false},
+		{&Error{Category: ErrorCategoryInvalidRequest, Err: syscall.ECONNRESET}, false},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{&url.Error{Op: "Post", URL: "https://api.openai.com", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}, true},
+		{&url.Error{Op: "Post", URL: "https://api.openai.com", Err: &net.OpError{Op: "dial", Err: timeoutError{}}}, true},
+		{&url.Error{Op: "Post", URL: "https://api.openai.com", Err: x509.UnknownAuthorityError{}}, false},
+		{&url.Error{Op: "Post", URL: "https://api.openai.com", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "api.openai.com", IsNotFound: true}}}, false},
+		{&url.Error{Op: "Post", URL: "https://api.openai.com", Err: context.DeadlineExceeded}, false},
+		{context.DeadlineExceeded, false},
+		{context.Canceled, false},
+		{errors.New("bad"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
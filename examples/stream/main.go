@@ -34,20 +34,14 @@ func main() {
 	}
 	defer stream.Close()
 
-	// Read the response stream
-	for {
-		resp, err := stream.Recv()
-		if err != nil {
-			panic(err)
+	// Print the response as it streams and collect the complete response
+	resp, err := aisuite.AccumulateStream(stream, func(chunk aisuite.ChatCompletionStreamResponse) {
+		if len(chunk.Choices) > 0 {
+			fmt.Print(chunk.Choices[0].Delta.Content)
 		}
-		if len(resp.Choices) == 0 {
-			fmt.Println("No choices")
-			break
-		}
-		if resp.Choices[0].FinishReason != "" {
-			fmt.Printf("\nStream finished: %s\n", resp.Choices[0].FinishReason)
-			break
-		}
-		fmt.Print(resp.Choices[0].Delta.Content)
+	})
+	if err != nil {
+		panic(err)
 	}
+	fmt.Printf("\nStream finished: %s\n", resp.Choices[0].FinishReason)
 }
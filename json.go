@@ -0,0 +1,29 @@
+package aisuite
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ErrNoJSON is returned by ExtractJSON when the content has no valid JSON value.
+var ErrNoJSON = errors.New("aisuite: no JSON value in content")
+
+// ExtractJSON returns the JSON value of a model reply. Surrounding
+// whitespace and markdown code fences (```json ... ```) are removed.
+func ExtractJSON(content string) (json.RawMessage, error) {
+	data := bytes.TrimSpace([]byte(content))
+	if bytes.HasPrefix(data, []byte("```")) {
+		// Drop the opening fence line, including its language tag.
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		} else {
+			data = data[3:]
+		}
+		data = bytes.TrimSpace(bytes.TrimSuffix(bytes.TrimSpace(data), []byte("```")))
+	}
+	if len(data) == 0 || !json.Valid(data) {
+		return nil, ErrNoJSON
+	}
+	return json.RawMessage(data), nil
+}
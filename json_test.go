@@ -0,0 +1,34 @@
+package aisuite
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`{"a":1}`, `{"a":1}`},
+		{"  [1, 2]\n", `[1, 2]`},
+		{"```json\n{\"a\":1}\n```", `{"a":1}`},
+		{"```\n{\"a\":1}\n```\n", `{"a":1}`},
+	}
+	for _, tt := range tests {
+		got, err := ExtractJSON(tt.content)
+		if err != nil {
+			t.Errorf("ExtractJSON(%q) error: %v", tt.content, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("ExtractJSON(%q) = %s, want %s", tt.content, got, tt.want)
+		}
+	}
+
+	for _, content := range []string{"", "Sure! {\"a\":1}", `{"a":`} {
+		if _, err := ExtractJSON(content); !errors.Is(err, ErrNoJSON) {
+			t.Errorf("ExtractJSON(%q) error = %v, want ErrNoJSON", content, err)
+		}
+	}
+}
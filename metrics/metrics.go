@@ -0,0 +1,248 @@
+// Package metrics instruments chat completions with Prometheus metrics.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers"
+)
+
+type Options struct {
+	// Namespace prefixes the metric names, defaults to "aisuite".
+	Namespace string
+	// DurationBuckets are the buckets of the request duration and time to
+	// first token histograms, in seconds.
+	DurationBuckets []float64
+	// TokenLatencyBuckets are the buckets of the inter-token latency
+	// histogram, in seconds.
+	TokenLatencyBuckets []float64
+}
+
+type Option func(o Options) Options
+
+func WithNamespace(namespace string) Option {
+	return func(o Options) Options {
+		o.Namespace = namespace
+		return o
+	}
+}
+
+func WithDurationBuckets(buckets []float64) Option {
+	return func(o Options) Options {
+		o.DurationBuckets = buckets
+		return o
+	}
+}
+
+func WithTokenLatencyBuckets(buckets []float64) Option {
+	return func(o Options) Options {
+		o.TokenLatencyBuckets = buckets
+		return o
+	}
+}
+
+// Metrics are the collectors of the instrumented clients, labeled by
+// provider and model.
+type Metrics struct {
+	requests          *prometheus.CounterVec
+	tokens            *prometheus.CounterVec
+	duration          *prometheus.HistogramVec
+	timeToFirstToken  *prometheus.HistogramVec
+	interTokenLatency *prometheus.HistogramVec
+	inFlight          *prometheus.GaugeVec
+}
+
+// NewMetrics creates the collectors and registers them on registerer.
+func NewMetrics(registerer prometheus.Registerer, opts ...Option) (*Metrics, error) {
+	o := Options{
+		Namespace:           "aisuite",
+		DurationBuckets:     []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 80},
+		TokenLatencyBuckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}
+	for _, opt := range opts {
+		o = opt(o)
+	}
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.Namespace,
+			Name:      "requests_total",
+			Help:      "Chat completion requests by outcome and error category.",
+		}, []string{"provider", "model", "operation", "outcome", "error_category"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.Namespace,
+			Name:      "tokens_total",
+			Help:      "Tokens used by chat completions, by type input or output.",
+		}, []string{"provider", "model", "type"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.Namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of chat completions, until the end of streams.",
+			Buckets:   o.DurationBuckets,
+		}, []string{"provider", "model", "operation"}),
+		timeToFirstToken: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.Namespace,
+			Name:      "time_to_first_token_seconds",
+			Help:      "Time from the request to the first chunk of streams.",
+			Buckets:   o.DurationBuckets,
+		}, []string{"provider", "model"}),
+		interTokenLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.Namespace,
+			Name:      "inter_token_latency_seconds",
+			Help:      "Time between the chunks of streams.",
+			Buckets:   o.TokenLatencyBuckets,
+		}, []string{"provider", "model"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: o.Namespace,
+			Name:      "requests_in_flight",
+			Help:      "Chat completions in progress, including open streams.",
+		}, []string{"provider", "model", "operation"}),
+	}
+	for _, c := range []prometheus.Collector{m.requests, m.tokens, m.duration, m.timeToFirstToken, m.interTokenLatency, m.inFlight} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Client records the metrics of the requests of a client.
+type Client struct {
+	client  aisuite.Client
+	metrics *Metrics
+}
+
+func New(client aisuite.Client, metrics *Metrics) *Client {
+	return &Client{client: client, metrics: metrics}
+}
+
+// Middleware returns a middleware recording metrics, see New.
+func Middleware(metrics *Metrics) aisuite.Middleware {
+	return func(next aisuite.Client) aisuite.Client {
+		return New(next, metrics)
+	}
+}
+
+const (
+	operationChat   = "chat"
+	operationStream = "stream"
+)
+
+// request records the metrics of a request.
+type request struct {
+	metrics   *Metrics
+	provider  string
+	model     string
+	operation string
+	start     time.Time
+}
+
+func (c *Client) start(model, operation string) *request {
+	provider, name, err := providers.ParseModel(model)
+	if err != nil {
+		provider, name = "", model
+	}
+	r := &request{metrics: c.metrics, provider: provider, model: name, operation: operation, start: time.Now()}
+	c.metrics.inFlight.WithLabelValues(provider, name, operation).Inc()
+	return r
+}
+
+func (r *request) end(usage *aisuite.Usage, err error) {
+	m := r.metrics
+	m.inFlight.WithLabelValues(r.provider, r.model, r.operation).Dec()
+	m.duration.WithLabelValues(r.provider, r.model, r.operation).Observe(time.Since(r.start).Seconds())
+	outcome, category := "success", ""
+	if err != nil {
+		outcome, category = "error", errorCategory(err)
+	}
+	m.requests.WithLabelValues(r.provider, r.model, r.operation, outcome, category).Inc()
+	if usage != nil {
+		m.tokens.WithLabelValues(r.provider, r.model, "input").Add(float64(usage.PromptTokens))
+		m.tokens.WithLabelValues(r.provider, r.model, "output").Add(float64(usage.CompletionTokens))
+	}
+}
+
+func errorCategory(err error) string {
+	var e *aisuite.Error
+	switch {
+	case errors.As(err, &e):
+		return string(e.Category)
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return string(aisuite.ErrorCategoryTimeout)
+	}
+	return string(aisuite.ErrorCategoryUnknown)
+}
+
+func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	r := c.start(req.Model, operationChat)
+	resp, err := c.client.ChatCompletion(ctx, req)
+	if err != nil {
+		r.end(nil, err)
+		return nil, err
+	}
+	r.end(&resp.Usage, nil)
+	return resp, nil
+}
+
+func (c *Client) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
+	r := c.start(req.Model, operationStream)
+	stream, err := c.client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		r.end(nil, err)
+		return nil, err
+	}
+	return &instrumentedStream{stream: stream, request: r}, nil
+}
+
+type instrumentedStream struct {
+	stream  aisuite.ChatCompletionStream
+	request *request
+	usage   *aisuite.Usage
+	last    time.Time
+	ended   bool
+}
+
+func (s *instrumentedStream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
+	chunk, err := s.stream.Recv()
+	if err != nil {
+		if err == io.EOF {
+			s.end(nil)
+		} else {
+			s.end(err)
+		}
+		return chunk, err
+	}
+	r := s.request
+	now := time.Now()
+	if s.last.IsZero() {
+		r.metrics.timeToFirstToken.WithLabelValues(r.provider, r.model).Observe(now.Sub(r.start).Seconds())
+	} else {
+		r.metrics.interTokenLatency.WithLabelValues(r.provider, r.model).Observe(now.Sub(s.last).Seconds())
+	}
+	s.last = now
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+	return chunk, nil
+}
+
+// Close ends the request of streams closed before the end, they are
+// counted as successful.
+func (s *instrumentedStream) Close() error {
+	s.end(nil)
+	return s.stream.Close()
+}
+
+func (s *instrumentedStream) end(err error) {
+	if !s.ended {
+		s.ended = true
+		s.request.end(s.usage, err)
+	}
+}
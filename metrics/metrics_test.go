@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers/mock"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := NewMetrics(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewMetrics(registry); err == nil {
+		t.Error("registered twice")
+	}
+	ctx := context.Background()
+	req := aisuite.ChatCompletionRequest{Model: "openai:gpt-4o-mini"}
+
+	reply := mock.Reply("Hello")
+	reply.Response.Usage = aisuite.Usage{PromptTokens: 9, CompletionTokens: 3, TotalTokens: 12}
+	chunks := mock.ReplyChunks(0, "Hel", "lo")
+	chunks.Chunks = append(chunks.Chunks, aisuite.ChatCompletionStreamResponse{
+		Usage: &aisuite.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+	})
+	client := New(mock.NewClient(reply, chunks), metrics)
+	if _, err = client.ChatCompletion(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(metrics.inFlight.WithLabelValues("openai", "gpt-4o-mini", "stream")); got != 1 {
+		t.Errorf("got %v streams in flight, want 1", got)
+	}
+	if _, err = aisuite.AccumulateStream(stream, nil); err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+
+	failing := New(mock.NewClient(
+		mock.Fail(&aisuite.Error{Category: aisuite.ErrorCategoryRateLimit}),
+		mock.Fail(errors.New("boom")),
+	), metrics)
+	_, _ = failing.ChatCompletion(ctx, req)
+	_, _ = failing.StreamChatCompletion(ctx, req)
+
+	tests := []struct {
+		collector prometheus.Collector
+		want      float64
+	}{
+		{metrics.requests.WithLabelValues("openai", "gpt-4o-mini", "chat", "success", ""), 1},
+		{metrics.requests.WithLabelValues("openai", "gpt-4o-mini", "stream", "success", ""), 1},
+		{metrics.requests.WithLabelValues("openai", "gpt-4o-mini", "chat", "error", "rate_limit"), 1},
+		{metrics.requests.WithLabelValues("openai", "gpt-4o-mini", "stream", "error", "unknown"), 1},
+		{metrics.tokens.WithLabelValues("openai", "gpt-4o-mini", "input"), 19},
+		{metrics.tokens.WithLabelValues("openai", "gpt-4o-mini", "output"), 5},
+		{metrics.inFlight.WithLabelValues("openai", "gpt-4o-mini", "stream"), 0},
+	}
+	for i, tt := range tests {
+		if got := testutil.ToFloat64(tt.collector); got != tt.want {
+			t.Errorf("metric %d: got %v, want %v", i, got, tt.want)
+		}
+	}
+	if got := testutil.CollectAndCount(metrics.timeToFirstToken); got != 1 {
+		t.Errorf("got %d time to first token series, want 1", got)
+	}
+	if got := testutil.CollectAndCount(registry, "aisuite_inter_token_latency_seconds"); got != 1 {
+		t.Errorf("got %d inter-token latency series, want 1", got)
+	}
+}
@@ -0,0 +1,50 @@
+package aisuite
+
+import "context"
+
+// Middleware wraps a client to intercept its requests, e.g. to log, retry or
+// cache them.
+type Middleware func(next Client) Client
+
+// Chain composes middlewares into one, the first middleware is the
+// outermost and sees requests first.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next Client) Client {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
+// ClientFuncs adapts functions to a Client, calls without function are
+// passed to Next. It helps writing middlewares intercepting only one call:
+//
+//	func(next aisuite.Client) aisuite.Client {
+//		return aisuite.ClientFuncs{
+//			Next: next,
+//			ChatCompletionFunc: func(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+//				log.Println("chat completion", req.Model)
+//				return next.ChatCompletion(ctx, req)
+//			},
+//		}
+//	}
+type ClientFuncs struct {
+	Next                     Client
+	ChatCompletionFunc       func(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error)
+	StreamChatCompletionFunc func(ctx context.Context, request ChatCompletionRequest) (ChatCompletionStream, error)
+}
+
+func (c ClientFuncs) ChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	if c.ChatCompletionFunc != nil {
+		return c.ChatCompletionFunc(ctx, request)
+	}
+	return c.Next.ChatCompletion(ctx, request)
+}
+
+func (c ClientFuncs) StreamChatCompletion(ctx context.Context, request ChatCompletionRequest) (ChatCompletionStream, error) {
+	if c.StreamChatCompletionFunc != nil {
+		return c.StreamChatCompletionFunc(ctx, request)
+	}
+	return c.Next.StreamChatCompletion(ctx, request)
+}
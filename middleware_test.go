@@ -0,0 +1,45 @@
+package aisuite
+
+import (
+	"context"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	tag := func(name string) Middleware {
+		return func(next Client) Client {
+			return ClientFuncs{
+				Next: next,
+				ChatCompletionFunc: func(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+					calls = append(calls, name)
+					req.Model += "/" + name
+					return next.ChatCompletion(ctx, req)
+				},
+			}
+		}
+	}
+	base := ClientFuncs{
+		ChatCompletionFunc: func(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+			return &ChatCompletionResponse{Model: req.Model}, nil
+		},
+		StreamChatCompletionFunc: func(ctx context.Context, req ChatCompletionRequest) (ChatCompletionStream, error) {
+			calls = append(calls, "stream")
+			return &sliceStream{}, nil
+		},
+	}
+	client := Chain(tag("a"), tag("b"))(base)
+	resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "m/a/b" {
+		t.Errorf("got model %q, want m/a/b", resp.Model)
+	}
+	if _, err := client.StreamChatCompletion(context.Background(), ChatCompletionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 3 || calls[0] != "a" || calls[1] != "b" || calls[2] != "stream" {
+		t.Errorf("got calls %v", calls)
+	}
+}
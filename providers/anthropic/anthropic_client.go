@@ -2,7 +2,14 @@ package anthropic
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"slices"
+	"strconv"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
@@ -20,15 +27,28 @@ type Client struct {
 }
 
 func NewClient(opts providers.Options) *Client {
-	return &Client{client: anthropic.NewClient(option.WithAPIKey(opts.Token))}
+	options := []option.RequestOption{option.WithAPIKey(opts.Token)}
+	if opts.DisableSDKRetries {
+		options = append(options, option.WithMaxRetries(0))
+	}
+	if opts.BaseURL != "" {
+		// The API path is resolved relative to the base URL, e.g.
+		// "https://host/anthropic/" sends requests to
+		// "https://host/anthropic/v1/messages".
+		options = append(options, option.WithBaseURL(opts.BaseURL))
+	}
+	if opts.HTTPClient != nil {
+		options = append(options, option.WithHTTPClient(opts.HTTPClient))
+	}
+	return &Client{client: anthropic.NewClient(options...)}
 }
 
-func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
-	messages := make([]anthropic.MessageParam, len(req.Messages))
-	for i, msg := range req.Messages {
-		if msg.Content != "" {
-			messages[i] = anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content))
-		}
+// toAnthropicParams converts a request to the params of both unary and
+// streamed requests. formatTool is the tool emulating the response format.
+func toAnthropicParams(req aisuite.ChatCompletionRequest) (params anthropic.MessageNewParams, formatTool string, err error) {
+	system, messages, err := toAnthropicMessages(req.Messages)
+	if err != nil {
+		return params, "", err
 	}
 
 	maxTokens := int64(req.MaxTokens)
@@ -36,112 +56,523 @@ func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionR
 		maxTokens = defaultMaxTokens
 	}
 
-	resp, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
+	params = anthropic.MessageNewParams{
 		Model:     anthropic.F(anthropic.Model(req.Model)),
 		Messages:  anthropic.F(messages),
 		MaxTokens: anthropic.F(maxTokens),
-	})
+	}
+	if len(system) > 0 {
+		params.System = anthropic.F(system)
+	}
+	setAnthropicTools(&params, req)
+	if err := setAnthropicSampling(&params, req); err != nil {
+		return params, "", err
+	}
+	formatTool, err = setAnthropicResponseFormat(&params, req)
+	return params, formatTool, err
+}
+
+func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	params, formatTool, err := toAnthropicParams(req)
 	if err != nil {
 		return nil, err
 	}
 
+	resp, err := c.client.Messages.New(ctx, params)
+	if err != nil {
+		return nil, toError(err)
+	}
+
 	content := ""
-	if len(resp.Content) > 0 {
-		content = resp.Content[0].Text
+	var jsonValue json.RawMessage
+	var toolCalls []aisuite.ToolCall
+	for _, block := range resp.Content {
+		switch block.Type {
+		case anthropic.ContentBlockTypeText:
+			content += block.Text
+		case anthropic.ContentBlockTypeToolUse:
+			if formatTool != "" && block.Name == formatTool {
+				// The arguments of the emulation tool are the reply.
+				content = string(block.Input)
+				jsonValue = block.Input
+				continue
+			}
+			toolCalls = append(toolCalls, aisuite.ToolCall{
+				Index: len(toolCalls),
+				ID:    block.ID,
+				Tool:  "function",
+				Function: aisuite.FunctionCall{
+					Name: block.Name,
+					Args: string(block.Input),
+				},
+			})
+		}
 	}
 
 	return &aisuite.ChatCompletionResponse{
+		ID:    resp.ID,
+		Model: resp.Model,
 		Choices: []aisuite.ChatCompletionChoice{
 			{
 				Message: aisuite.ChatCompletionMessage{
-					Role:    fromAnthropicRole(resp.Role),
-					Content: content,
+					Role:      fromAnthropicRole(resp.Role),
+					Content:   content,
+					ToolCalls: toolCalls,
 				},
+				FinishReason: fromAnthropicResponseStopReason(anthropic.MessageDeltaEventDeltaStopReason(resp.StopReason), formatTool),
+				JSON:         jsonValue,
 			},
 		},
+		Usage: fromAnthropicUsage(resp.Usage),
 	}, nil
 }
 
 func (c *Client) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
-	system := make([]anthropic.TextBlockParam, 0, 1)
-	messages := make([]anthropic.MessageParam, 0, len(req.Messages))
-	for _, msg := range req.Messages {
-		switch msg.Role {
-		case aisuite.RoleSystem:
-			system = append(system, anthropic.NewTextBlock(msg.Content))
-		case aisuite.RoleUser:
-			messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)))
-		case aisuite.RoleAssistant:
-			messages = append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(msg.Content)))
-		default:
-			messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)))
-		}
+	params, formatTool, err := toAnthropicParams(req)
+	if err != nil {
+		return nil, err
 	}
 
-	maxTokens := int64(req.MaxTokens)
-	if maxTokens == 0 {
-		maxTokens = defaultMaxTokens
+	stream := c.client.Messages.NewStreaming(ctx, params)
+	if err := stream.Err(); err != nil {
+		return nil, toError(err)
 	}
 
-	stream := c.client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.F(anthropic.Model(req.Model)),
-		System:    anthropic.F(system),
-		Messages:  anthropic.F(messages),
-		MaxTokens: anthropic.F(maxTokens),
-	})
-
 	return &chatCompletionStream{
-		stream: stream,
+		stream:     stream,
+		formatTool: formatTool,
 	}, nil
 }
 
 type chatCompletionStream struct {
 	stream *ssestream.Stream[anthropic.MessageStreamEvent]
+	id     string
+	model  string
+	usage  aisuite.Usage
+	// toolIndex maps content block indexes to tool call indexes.
+	toolIndex map[int64]int
+	// formatTool is the tool emulating the response format, the arguments
+	// of its content block are streamed as content.
+	formatTool  string
+	formatBlock map[int64]bool
 }
 
 func (s *chatCompletionStream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
-	for {
-		if !s.stream.Next() {
-			continue
-		}
-		if err := s.stream.Err(); err != nil {
-			return aisuite.ChatCompletionStreamResponse{}, err
-		}
-
+	for s.stream.Next() {
 		event := s.stream.Current()
 
 		switch event.Type {
+		case anthropic.MessageStreamEventTypeMessageStart:
+			s.id = event.Message.ID
+			s.model = event.Message.Model
+			s.usage = fromAnthropicUsage(event.Message.Usage)
 		case anthropic.MessageStreamEventTypeMessageDelta:
-			event := event.Delta.(anthropic.MessageDeltaEventDelta)
-			if event.StopReason != "" {
-				return aisuite.ChatCompletionStreamResponse{
-					Choices: []aisuite.ChatCompletionStreamChoice{
-						{
-							FinishReason: fromAnthropicStopReason(event.StopReason),
-						},
-					},
-				}, nil
+			// The output tokens of message_delta are cumulative.
+			s.usage.CompletionTokens = int(event.Usage.OutputTokens)
+			s.usage.TotalTokens = s.usage.PromptTokens + s.usage.CompletionTokens
+			delta := event.Delta.(anthropic.MessageDeltaEventDelta)
+			if delta.StopReason != "" {
+				resp := s.response(aisuite.ChatCompletionStreamChoice{
+					FinishReason: fromAnthropicResponseStopReason(delta.StopReason, s.formatTool),
+				})
+				usage := s.usage
+				resp.Usage = &usage
+				return resp, nil
 			}
+		case anthropic.MessageStreamEventTypeContentBlockStart:
+			block := event.ContentBlock.(anthropic.ContentBlockStartEventContentBlock)
+			if block.Type != anthropic.ContentBlockStartEventContentBlockTypeToolUse {
+				continue
+			}
+			if s.formatTool != "" && block.Name == s.formatTool {
+				if s.formatBlock == nil {
+					s.formatBlock = make(map[int64]bool)
+				}
+				s.formatBlock[event.Index] = true
+				continue
+			}
+			if s.toolIndex == nil {
+				s.toolIndex = make(map[int64]int)
+			}
+			index := len(s.toolIndex)
+			s.toolIndex[event.Index] = index
+			return s.toolCallResponse(aisuite.ToolCall{
+				Index: index,
+				ID:    block.ID,
+				Tool:  "function",
+				Function: aisuite.FunctionCall{
+					Name: block.Name,
+				},
+			}), nil
 		case anthropic.MessageStreamEventTypeContentBlockDelta:
 			delta := event.Delta.(anthropic.ContentBlockDeltaEventDelta)
-			return aisuite.ChatCompletionStreamResponse{
-				Choices: []aisuite.ChatCompletionStreamChoice{
-					{
-						Delta: aisuite.ChatCompletionStreamChoiceDelta{
-							Role:    aisuite.RoleAssistant,
-							Content: delta.Text,
-						},
+			if delta.Type == anthropic.ContentBlockDeltaEventDeltaTypeInputJSONDelta && s.formatBlock[event.Index] {
+				return s.response(aisuite.ChatCompletionStreamChoice{
+					Delta: aisuite.ChatCompletionStreamChoiceDelta{
+						Role:    aisuite.RoleAssistant,
+						Content: delta.PartialJSON,
 					},
+				}), nil
+			}
+			if delta.Type == anthropic.ContentBlockDeltaEventDeltaTypeInputJSONDelta {
+				index, ok := s.toolIndex[event.Index]
+				if !ok || delta.PartialJSON == "" {
+					continue
+				}
+				return s.toolCallResponse(aisuite.ToolCall{
+					Index:    index,
+					Function: aisuite.FunctionCall{Args: delta.PartialJSON},
+				}), nil
+			}
+			return s.response(aisuite.ChatCompletionStreamChoice{
+				Delta: aisuite.ChatCompletionStreamChoiceDelta{
+					Role:    aisuite.RoleAssistant,
+					Content: delta.Text,
 				},
-			}, nil
+			}), nil
 		}
 	}
+	if err := s.stream.Err(); err != nil {
+		return aisuite.ChatCompletionStreamResponse{}, toError(err)
+	}
+	return aisuite.ChatCompletionStreamResponse{}, io.EOF
+}
+
+func (s *chatCompletionStream) response(choice aisuite.ChatCompletionStreamChoice) aisuite.ChatCompletionStreamResponse {
+	return aisuite.ChatCompletionStreamResponse{
+		ID:      s.id,
+		Model:   s.model,
+		Choices: []aisuite.ChatCompletionStreamChoice{choice},
+	}
+}
+
+func (s *chatCompletionStream) toolCallResponse(toolCall aisuite.ToolCall) aisuite.ChatCompletionStreamResponse {
+	return s.response(aisuite.ChatCompletionStreamChoice{
+		Delta: aisuite.ChatCompletionStreamChoiceDelta{
+			Role:      aisuite.RoleAssistant,
+			ToolCalls: []aisuite.ToolCall{toolCall},
+		},
+	})
 }
 
 func (s *chatCompletionStream) Close() error {
 	return s.stream.Close()
 }
 
+// toAnthropicMessages splits system messages from the conversation and
+// converts the remaining messages to Anthropic message params. Tool results
+// are sent as tool_result blocks in user turns, empty messages are dropped
+// and consecutive turns of the same role are merged, as Anthropic requires
+// alternating user and assistant turns starting with a user turn.
+//
+// Conversations Anthropic can't represent are errors: no turn, a first
+// assistant turn, tool results not answering the tool calls of the previous
+// assistant turn, or tool calls without result.
+func toAnthropicMessages(msgs []aisuite.ChatCompletionMessage) ([]anthropic.TextBlockParam, []anthropic.MessageParam, error) {
+	system := make([]anthropic.TextBlockParam, 0, 1)
+	messages := make([]anthropic.MessageParam, 0, len(msgs))
+	// pending are the tool calls of the last assistant turn without result.
+	var pending []string
+	for i, msg := range msgs {
+		switch msg.Role {
+		case aisuite.RoleSystem:
+			for _, part := range msg.Parts() {
+				if part.Type != aisuite.ContentPartTypeText {
+					return nil, nil, fmt.Errorf("%w: anthropic does not support %q parts in system messages", aisuite.ErrUnsupportedContentPart, part.Type)
+				}
+				system = append(system, anthropic.NewTextBlock(part.Text))
+			}
+		case aisuite.RoleAssistant:
+			blocks, err := toAnthropicBlocks(msg.Parts())
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, toolCall := range msg.ToolCalls {
+				input := json.RawMessage(toolCall.Function.Args)
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				} else if !json.Valid(input) {
+					return nil, nil, fmt.Errorf("anthropic: invalid arguments of tool call %q in message %d", toolCall.ID, i)
+				}
+				blocks = append(blocks, anthropic.NewToolUseBlockParam(toolCall.ID, toolCall.Function.Name, input))
+			}
+			if len(blocks) == 0 {
+				continue
+			}
+			if len(pending) > 0 {
+				return nil, nil, fmt.Errorf("anthropic: tool call %q has no result before message %d", pending[0], i)
+			}
+			if len(messages) == 0 {
+				return nil, nil, fmt.Errorf("anthropic: message %d is an assistant turn, the conversation must start with a user turn", i)
+			}
+			messages = appendTurn(messages, anthropic.MessageParamRoleAssistant, blocks)
+			for _, toolCall := range msg.ToolCalls {
+				pending = append(pending, toolCall.ID)
+			}
+		case aisuite.RoleTool:
+			if msg.ToolCallID == "" {
+				return nil, nil, fmt.Errorf("anthropic: tool message %d has no tool call ID", i)
+			}
+			index := slices.Index(pending, msg.ToolCallID)
+			if index < 0 {
+				return nil, nil, fmt.Errorf("anthropic: tool message %d doesn't answer a tool call of the previous assistant turn", i)
+			}
+			pending = slices.Delete(pending, index, index+1)
+			result, err := toAnthropicToolResult(msg)
+			if err != nil {
+				return nil, nil, err
+			}
+			messages = appendTurn(messages, anthropic.MessageParamRoleUser, []anthropic.ContentBlockParamUnion{result})
+		default:
+			blocks, err := toAnthropicBlocks(msg.Parts())
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(blocks) == 0 {
+				continue
+			}
+			if len(pending) > 0 {
+				return nil, nil, fmt.Errorf("anthropic: tool call %q has no result before message %d", pending[0], i)
+			}
+			messages = appendTurn(messages, anthropic.MessageParamRoleUser, blocks)
+		}
+	}
+	if len(pending) > 0 {
+		return nil, nil, fmt.Errorf("anthropic: tool call %q has no result", pending[0])
+	}
+	if len(messages) == 0 {
+		return nil, nil, errors.New("anthropic: no user or assistant message")
+	}
+	return system, messages, nil
+}
+
+// toAnthropicToolResult converts a tool message, its text and image parts
+// are the content of the result.
+func toAnthropicToolResult(msg aisuite.ChatCompletionMessage) (anthropic.ToolResultBlockParam, error) {
+	result := anthropic.ToolResultBlockParam{
+		Type:      anthropic.F(anthropic.ToolResultBlockParamTypeToolResult),
+		ToolUseID: anthropic.F(msg.ToolCallID),
+		IsError:   anthropic.F(false),
+	}
+	var content []anthropic.ToolResultBlockParamContentUnion
+	for _, part := range msg.Parts() {
+		switch part.Type {
+		case aisuite.ContentPartTypeText:
+			if part.Text == "" {
+				continue
+			}
+			content = append(content, anthropic.NewTextBlock(part.Text))
+		case aisuite.ContentPartTypeImage:
+			content = append(content, anthropic.NewImageBlockBase64(part.MediaType, base64.StdEncoding.EncodeToString(part.Data)))
+		case aisuite.ContentPartTypeImageURL:
+			content = append(content, anthropic.ToolResultBlockParamContent{
+				Type:   anthropic.F(anthropic.ToolResultBlockParamContentTypeImage),
+				Source: anthropic.F[any](map[string]string{"type": "url", "url": part.URL}),
+			})
+		default:
+			return result, fmt.Errorf("%w: anthropic does not support %q parts in tool messages", aisuite.ErrUnsupportedContentPart, part.Type)
+		}
+	}
+	if len(content) > 0 {
+		result.Content = anthropic.F(content)
+	}
+	return result, nil
+}
+
+// appendTurn appends blocks to the last turn if it has the same role, as a
+// new turn otherwise.
+func appendTurn(messages []anthropic.MessageParam, role anthropic.MessageParamRole, blocks []anthropic.ContentBlockParamUnion) []anthropic.MessageParam {
+	if n := len(messages); n > 0 && messages[n-1].Role.Value == role {
+		last := &messages[n-1]
+		last.Content = anthropic.F(append(last.Content.Value, blocks...))
+		return messages
+	}
+	return append(messages, anthropic.MessageParam{
+		Role:    anthropic.F(role),
+		Content: anthropic.F(blocks),
+	})
+}
+
+func toAnthropicBlocks(parts []aisuite.ContentPart) ([]anthropic.ContentBlockParamUnion, error) {
+	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case aisuite.ContentPartTypeText:
+			if part.Text == "" {
+				// Anthropic rejects empty text blocks.
+				continue
+			}
+			blocks = append(blocks, anthropic.NewTextBlock(part.Text))
+		case aisuite.ContentPartTypeImage:
+			blocks = append(blocks, anthropic.NewImageBlockBase64(part.MediaType, base64.StdEncoding.EncodeToString(part.Data)))
+		case aisuite.ContentPartTypeImageURL:
+			blocks = append(blocks, anthropic.ContentBlockParam{
+				Type:   anthropic.F(anthropic.ContentBlockParamTypeImage),
+				Source: anthropic.F[any](map[string]string{"type": "url", "url": part.URL}),
+			})
+		case aisuite.ContentPartTypeDocument:
+			// Plain text documents are sent as text sources, others (PDF) as base64.
+			source := map[string]string{"type": "base64", "media_type": part.MediaType}
+			if part.MediaType == "text/plain" {
+				source["type"] = "text"
+				source["data"] = string(part.Data)
+			} else {
+				source["data"] = base64.StdEncoding.EncodeToString(part.Data)
+			}
+			blocks = append(blocks, anthropic.ContentBlockParam{
+				Type:   anthropic.F(anthropic.ContentBlockParamType("document")),
+				Source: anthropic.F[any](source),
+			})
+		default:
+			return nil, fmt.Errorf("%w: anthropic does not support %q parts", aisuite.ErrUnsupportedContentPart, part.Type)
+		}
+	}
+	return blocks, nil
+}
+
+func setAnthropicTools(params *anthropic.MessageNewParams, req aisuite.ChatCompletionRequest) {
+	if len(req.Tools) == 0 {
+		return
+	}
+	tools := make([]anthropic.ToolParam, len(req.Tools))
+	for i, tool := range req.Tools {
+		var schema any = tool.Parameters
+		if schema == nil {
+			// Anthropic requires an input schema even for tools without arguments.
+			schema = map[string]any{"type": "object"}
+		}
+		tools[i] = anthropic.ToolParam{
+			Name:        anthropic.F(tool.Name),
+			Description: anthropic.F(tool.Description),
+			InputSchema: anthropic.F(schema),
+		}
+	}
+	params.Tools = anthropic.F(tools)
+	if choice := toAnthropicToolChoice(req.ToolChoice); choice != nil {
+		params.ToolChoice = anthropic.F(choice)
+	}
+}
+
+func setAnthropicSampling(params *anthropic.MessageNewParams, req aisuite.ChatCompletionRequest) error {
+	switch {
+	case req.Seed != nil:
+		return &aisuite.UnsupportedParameterError{Provider: Name, Parameter: "seed"}
+	case req.PresencePenalty != nil:
+		return &aisuite.UnsupportedParameterError{Provider: Name, Parameter: "presence_penalty"}
+	case req.FrequencyPenalty != nil:
+		return &aisuite.UnsupportedParameterError{Provider: Name, Parameter: "frequency_penalty"}
+	case len(req.LogitBias) > 0:
+		return &aisuite.UnsupportedParameterError{Provider: Name, Parameter: "logit_bias"}
+	case req.N > 1:
+		return &aisuite.UnsupportedParameterError{Provider: Name, Parameter: "n"}
+	}
+	if req.Temperature != nil {
+		params.Temperature = anthropic.F(*req.Temperature)
+	}
+	if req.TopP != nil {
+		params.TopP = anthropic.F(*req.TopP)
+	}
+	if req.TopK != nil {
+		params.TopK = anthropic.F(int64(*req.TopK))
+	}
+	if len(req.Stop) > 0 {
+		params.StopSequences = anthropic.F(req.Stop)
+	}
+	return nil
+}
+
+// responseFormatTool is the default name of the tool emulating JSON
+// response formats.
+const responseFormatTool = "json_response"
+
+// setAnthropicResponseFormat emulates JSON response formats with a forced
+// tool call whose arguments are the reply. It returns the name of the tool,
+// or "" when no emulation is needed.
+func setAnthropicResponseFormat(params *anthropic.MessageNewParams, req aisuite.ChatCompletionRequest) (string, error) {
+	format := req.ResponseFormat
+	if !format.IsJSON() {
+		return "", nil
+	}
+	if len(req.Tools) > 0 {
+		return "", &aisuite.UnsupportedParameterError{Provider: Name, Parameter: "response_format with tools"}
+	}
+	name := format.Name
+	if name == "" {
+		name = responseFormatTool
+	}
+	description := format.Description
+	if description == "" {
+		description = "Reply with a JSON value matching the input schema."
+	}
+	schema := format.Schema
+	if format.Type == aisuite.ResponseFormatTypeJSONObject || schema == nil {
+		schema = map[string]any{"type": "object"}
+	}
+	params.Tools = anthropic.F([]anthropic.ToolParam{
+		{
+			Name:        anthropic.F(name),
+			Description: anthropic.F(description),
+			InputSchema: anthropic.F(schema),
+		},
+	})
+	params.ToolChoice = anthropic.F[anthropic.ToolChoiceUnionParam](anthropic.ToolChoiceToolParam{
+		Type: anthropic.F(anthropic.ToolChoiceToolTypeTool),
+		Name: anthropic.F(name),
+	})
+	return name, nil
+}
+
+func toAnthropicToolChoice(choice aisuite.ToolChoice) anthropic.ToolChoiceUnionParam {
+	switch choice.Mode {
+	case aisuite.ToolChoiceAuto:
+		return anthropic.ToolChoiceAutoParam{Type: anthropic.F(anthropic.ToolChoiceAutoTypeAuto)}
+	case aisuite.ToolChoiceRequired:
+		return anthropic.ToolChoiceAnyParam{Type: anthropic.F(anthropic.ToolChoiceAnyTypeAny)}
+	case aisuite.ToolChoiceFunction:
+		return anthropic.ToolChoiceToolParam{
+			Type: anthropic.F(anthropic.ToolChoiceToolTypeTool),
+			Name: anthropic.F(choice.Name),
+		}
+	case aisuite.ToolChoiceNone:
+		return anthropic.ToolChoiceParam{Type: anthropic.F(anthropic.ToolChoiceType("none"))}
+	}
+	return nil
+}
+
+// fromAnthropicUsage converts Anthropic usage, where input tokens exclude
+// the tokens read from and written to the prompt cache.
+func fromAnthropicUsage(usage anthropic.Usage) aisuite.Usage {
+	cacheRead := extraTokens(usage, "cache_read_input_tokens")
+	cacheCreation := extraTokens(usage, "cache_creation_input_tokens")
+	u := aisuite.Usage{
+		PromptTokens:           int(usage.InputTokens) + cacheRead + cacheCreation,
+		CompletionTokens:       int(usage.OutputTokens),
+		CachedPromptTokens:     cacheRead,
+		CacheWritePromptTokens: cacheCreation,
+	}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	return u
+}
+
+// extraTokens reads token counts not modeled by the SDK version in use.
+func extraTokens(usage anthropic.Usage, name string) int {
+	field, ok := usage.JSON.ExtraFields[name]
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.Atoi(field.Raw())
+	return n
+}
+
+// fromAnthropicResponseStopReason reports the forced call of the response
+// format tool as a normal stop.
+func fromAnthropicResponseStopReason(stopReason anthropic.MessageDeltaEventDeltaStopReason, formatTool string) aisuite.FinishReason {
+	if formatTool != "" && stopReason == anthropic.MessageDeltaEventDeltaStopReasonToolUse {
+		return aisuite.FinishReasonStop
+	}
+	return fromAnthropicStopReason(stopReason)
+}
+
 func fromAnthropicStopReason(stopReason anthropic.MessageDeltaEventDeltaStopReason) aisuite.FinishReason {
 	switch stopReason {
 	case "":
@@ -150,6 +581,10 @@ func fromAnthropicStopReason(stopReason anthropic.MessageDeltaEventDeltaStopReas
 		return aisuite.FinishReasonStop
 	case "max_tokens":
 		return aisuite.FinishReasonMaxTokens
+	case "stop_sequence":
+		return aisuite.FinishReasonStop
+	case "tool_use":
+		return aisuite.FinishReasonToolCalls
 	case "content_filter":
 		return aisuite.FinishReasonContentFilter
 	default:
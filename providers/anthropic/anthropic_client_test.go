@@ -0,0 +1,529 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/cassette"
+	"github.com/cpunion/go-aisuite/providers"
+)
+
+func TestSetAnthropicTools(t *testing.T) {
+	tests := []struct {
+		choice aisuite.ToolChoice
+		want   string
+	}{
+		{aisuite.ToolChoice{}, `null`},
+		{aisuite.ToolChoice{Mode: aisuite.ToolChoiceAuto}, `{"type":"auto"}`},
+		{aisuite.ToolChoice{Mode: aisuite.ToolChoiceNone}, `{"type":"none"}`},
+		{aisuite.ToolChoice{Mode: aisuite.ToolChoiceRequired}, `{"type":"any"}`},
+		{aisuite.ToolChoice{Mode: aisuite.ToolChoiceFunction, Name: "get_weather"}, `{"name":"get_weather","type":"tool"}`},
+	}
+	for _, tt := range tests {
+		params := anthropic.MessageNewParams{}
+		setAnthropicTools(&params, aisuite.ChatCompletionRequest{
+			Tools:      []aisuite.Tool{{Name: "get_weather", Description: "Get the weather of a city"}},
+			ToolChoice: tt.choice,
+		})
+		data, err := json.Marshal(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			Tools []struct {
+				Name        string          `json:"name"`
+				InputSchema json.RawMessage `json:"input_schema"`
+			} `json:"tools"`
+			ToolChoice json.RawMessage `json:"tool_choice"`
+		}
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Tools) != 1 || got.Tools[0].Name != "get_weather" || string(got.Tools[0].InputSchema) != `{"type":"object"}` {
+			t.Errorf("unexpected tools: %s", data)
+		}
+		if tc := string(got.ToolChoice); tc != tt.want && !(tt.want == "null" && tc == "") {
+			t.Errorf("tool choice for %v = %s, want %s", tt.choice, tc, tt.want)
+		}
+	}
+}
+
+func TestToAnthropicMessagesToolResults(t *testing.T) {
+	system, messages, err := toAnthropicMessages([]aisuite.ChatCompletionMessage{
+		{Role: aisuite.RoleSystem, Content: "You are a weather bot."},
+		{Role: aisuite.RoleUser, Content: "Weather in Paris and Rome?"},
+		{
+			Role: aisuite.RoleAssistant,
+			ToolCalls: []aisuite.ToolCall{
+				{ID: "call_1", Tool: "function", Function: aisuite.FunctionCall{Name: "get_weather", Args: `{"city":"Paris"}`}},
+				{ID: "call_2", Tool: "function", Function: aisuite.FunctionCall{Name: "get_weather", Args: `{"city":"Rome"}`}},
+			},
+		},
+		{Role: aisuite.RoleTool, ToolCallID: "call_1", Content: "sunny"},
+		{Role: aisuite.RoleTool, ToolCallID: "call_2", Content: "rainy"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(system) != 1 {
+		t.Fatalf("got %d system blocks, want 1", len(system))
+	}
+	data, err := json.Marshal(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"content":[{"text":"Weather in Paris and Rome?","type":"text"}],"role":"user"},` +
+		`{"content":[{"id":"call_1","input":{"city":"Paris"},"name":"get_weather","type":"tool_use"},` +
+		`{"id":"call_2","input":{"city":"Rome"},"name":"get_weather","type":"tool_use"}],"role":"assistant"},` +
+		`{"content":[{"content":[{"text":"sunny","type":"text"}],"is_error":false,"tool_use_id":"call_1","type":"tool_result"},` +
+		`{"content":[{"text":"rainy","type":"text"}],"is_error":false,"tool_use_id":"call_2","type":"tool_result"}],"role":"user"}]`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
+
+func TestToAnthropicMessagesErrors(t *testing.T) {
+	tests := [][]aisuite.ChatCompletionMessage{
+		{{Role: aisuite.RoleTool, Content: "sunny"}},
+		{{Role: aisuite.RoleAssistant, ToolCalls: []aisuite.ToolCall{{ID: "call_1", Function: aisuite.FunctionCall{Args: "{"}}}}},
+		{},
+		{{Role: aisuite.RoleSystem, Content: "Be brief."}, {Role: aisuite.RoleUser}},
+		{{Role: aisuite.RoleAssistant, Content: "Hello!"}, {Role: aisuite.RoleUser, Content: "Hi"}},
+		{
+			{Role: aisuite.RoleUser, Content: "Weather?"},
+			{Role: aisuite.RoleAssistant, ToolCalls: []aisuite.ToolCall{{ID: "call_1", Function: aisuite.FunctionCall{Name: "get_weather"}}}},
+			{Role: aisuite.RoleTool, ToolCallID: "call_2", Content: "sunny"},
+		},
+		{
+			{Role: aisuite.RoleUser, Content: "Weather?"},
+			{Role: aisuite.RoleAssistant, ToolCalls: []aisuite.ToolCall{{ID: "call_1", Function: aisuite.FunctionCall{Name: "get_weather"}}}},
+			{Role: aisuite.RoleUser, Content: "Never mind"},
+		},
+		{
+			{Role: aisuite.RoleUser, Content: "Weather?"},
+			{Role: aisuite.RoleAssistant, ToolCalls: []aisuite.ToolCall{{ID: "call_1", Function: aisuite.FunctionCall{Name: "get_weather"}}}},
+		},
+	}
+	for _, msgs := range tests {
+		if _, _, err := toAnthropicMessages(msgs); err == nil {
+			t.Errorf("toAnthropicMessages(%v) got nil error", msgs)
+		}
+	}
+}
+
+func TestToAnthropicMessagesTurns(t *testing.T) {
+	system, messages, err := toAnthropicMessages([]aisuite.ChatCompletionMessage{
+		{Role: aisuite.RoleUser, Content: "Hi"},
+		{Role: aisuite.RoleSystem, Content: "Be brief."},
+		{Role: aisuite.RoleUser, Content: "Weather in Paris?"},
+		{Role: aisuite.RoleAssistant},
+		{Role: aisuite.RoleAssistant, Content: "Let me check."},
+		{Role: aisuite.RoleAssistant, ToolCalls: []aisuite.ToolCall{{ID: "call_1", Function: aisuite.FunctionCall{Name: "get_weather", Args: `{"city":"Paris"}`}}}},
+		{Role: aisuite.RoleTool, ToolCallID: "call_1", Content: "sunny"},
+		{Role: aisuite.RoleUser, Content: "Thanks"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(system) != 1 || system[0].Text.Value != "Be brief." {
+		t.Errorf("unexpected system %+v", system)
+	}
+	data, err := json.Marshal(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"content":[{"text":"Hi","type":"text"},{"text":"Weather in Paris?","type":"text"}],"role":"user"},` +
+		`{"content":[{"text":"Let me check.","type":"text"},{"id":"call_1","input":{"city":"Paris"},"name":"get_weather","type":"tool_use"}],"role":"assistant"},` +
+		`{"content":[{"content":[{"text":"sunny","type":"text"}],"is_error":false,"tool_use_id":"call_1","type":"tool_result"},{"text":"Thanks","type":"text"}],"role":"user"}]`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
+
+func TestToAnthropicMessagesMultiContent(t *testing.T) {
+	_, messages, err := toAnthropicMessages([]aisuite.ChatCompletionMessage{
+		{
+			Role:    aisuite.RoleUser,
+			Content: "Summarize",
+			MultiContent: []aisuite.ContentPart{
+				aisuite.NewImagePart("image/png", []byte("png")),
+				aisuite.NewImageURLPart("https://example.com/cat.png"),
+				aisuite.NewDocumentPart("application/pdf", []byte("%PDF")),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"content":[{"text":"Summarize","type":"text"},` +
+		`{"source":{"data":"cG5n","media_type":"image/png","type":"base64"},"type":"image"},` +
+		`{"source":{"type":"url","url":"https://example.com/cat.png"},"type":"image"},` +
+		`{"source":{"data":"JVBERg==","media_type":"application/pdf","type":"base64"},"type":"document"}],"role":"user"}]`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+
+	_, _, err = toAnthropicMessages([]aisuite.ChatCompletionMessage{
+		{Role: aisuite.RoleUser, MultiContent: []aisuite.ContentPart{aisuite.NewAudioPart("audio/wav", []byte("RIFF"))}},
+	})
+	if !errors.Is(err, aisuite.ErrUnsupportedContentPart) {
+		t.Errorf("got error %v, want ErrUnsupportedContentPart", err)
+	}
+}
+
+func TestToAnthropicMessagesToolResultMultiContent(t *testing.T) {
+	call := []aisuite.ChatCompletionMessage{
+		{Role: aisuite.RoleUser, Content: "Draw a cat"},
+		{Role: aisuite.RoleAssistant, ToolCalls: []aisuite.ToolCall{{ID: "call_1", Function: aisuite.FunctionCall{Name: "draw"}}}},
+	}
+	_, messages, err := toAnthropicMessages(append(call, aisuite.ChatCompletionMessage{
+		Role:       aisuite.RoleTool,
+		ToolCallID: "call_1",
+		MultiContent: []aisuite.ContentPart{
+			aisuite.NewTextPart("Here it is"),
+			aisuite.NewImagePart("image/png", []byte("png")),
+			aisuite.NewImageURLPart("https://example.com/cat.png"),
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(messages[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"content":[{"content":[{"text":"Here it is","type":"text"},` +
+		`{"source":{"data":"cG5n","media_type":"image/png","type":"base64"},"type":"image"},` +
+		`{"source":{"type":"url","url":"https://example.com/cat.png"},"type":"image"}],` +
+		`"is_error":false,"tool_use_id":"call_1","type":"tool_result"}],"role":"user"}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+
+	_, _, err = toAnthropicMessages(append(call, aisuite.ChatCompletionMessage{
+		Role:         aisuite.RoleTool,
+		ToolCallID:   "call_1",
+		MultiContent: []aisuite.ContentPart{aisuite.NewDocumentPart("application/pdf", []byte("%PDF"))},
+	}))
+	if !errors.Is(err, aisuite.ErrUnsupportedContentPart) {
+		t.Errorf("got error %v, want ErrUnsupportedContentPart", err)
+	}
+}
+
+func newTestStream(events string) *chatCompletionStream {
+	res := &http.Response{
+		Header: http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:   io.NopCloser(strings.NewReader(events)),
+	}
+	return &chatCompletionStream{
+		stream: ssestream.NewStream[anthropic.MessageStreamEvent](ssestream.NewDecoder(res), nil),
+	}
+}
+
+func TestChatCompletionStreamToolUse(t *testing.T) {
+	stream := newTestStream(`event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-3-5-haiku-20241022","stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me check."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":" \"Paris\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":15}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`)
+	var content string
+	var finishReason aisuite.FinishReason
+	var usage *aisuite.Usage
+	var acc aisuite.ToolCallAccumulator
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Usage != nil {
+			usage = resp.Usage
+		}
+		for _, choice := range resp.Choices {
+			content += choice.Delta.Content
+			acc.Add(choice.Delta.ToolCalls...)
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+	}
+	if content != "Let me check." {
+		t.Errorf("got content %q", content)
+	}
+	if finishReason != aisuite.FinishReasonToolCalls {
+		t.Errorf("got finish reason %q", finishReason)
+	}
+	if want := (aisuite.Usage{PromptTokens: 25, CompletionTokens: 15, TotalTokens: 40}); usage == nil || *usage != want {
+		t.Errorf("got usage %+v, want %+v", usage, want)
+	}
+	toolCalls, err := acc.ToolCalls()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []aisuite.ToolCall{
+		{Index: 0, ID: "toolu_1", Tool: "function", Function: aisuite.FunctionCall{Name: "get_weather", Args: `{"city": "Paris"}`}},
+	}
+	if !reflect.DeepEqual(toolCalls, want) {
+		t.Errorf("got tool calls %+v, want %+v", toolCalls, want)
+	}
+}
+
+func TestFromAnthropicUsage(t *testing.T) {
+	var usage anthropic.Usage
+	data := `{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":100,"cache_creation_input_tokens":20}`
+	if err := json.Unmarshal([]byte(data), &usage); err != nil {
+		t.Fatal(err)
+	}
+	want := aisuite.Usage{PromptTokens: 130, CompletionTokens: 5, TotalTokens: 135, CachedPromptTokens: 100, CacheWritePromptTokens: 20}
+	if got := fromAnthropicUsage(usage); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSetAnthropicSampling(t *testing.T) {
+	params := anthropic.MessageNewParams{}
+	err := setAnthropicSampling(&params, aisuite.ChatCompletionRequest{
+		Temperature: aisuite.Ptr(0.0),
+		TopP:        aisuite.Ptr(0.9),
+		TopK:        aisuite.Ptr(40),
+		Stop:        []string{"END"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["temperature"] != 0.0 || got["top_p"] != 0.9 || got["top_k"] != 40.0 || got["stop_sequences"].([]any)[0] != "END" {
+		t.Errorf("unexpected params %s", data)
+	}
+
+	tests := []struct {
+		req  aisuite.ChatCompletionRequest
+		want string
+	}{
+		{aisuite.ChatCompletionRequest{Seed: aisuite.Ptr(1)}, "seed"},
+		{aisuite.ChatCompletionRequest{PresencePenalty: aisuite.Ptr(0.5)}, "presence_penalty"},
+		{aisuite.ChatCompletionRequest{FrequencyPenalty: aisuite.Ptr(0.5)}, "frequency_penalty"},
+		{aisuite.ChatCompletionRequest{LogitBias: map[string]int{"1": 1}}, "logit_bias"},
+		{aisuite.ChatCompletionRequest{N: 2}, "n"},
+	}
+	for _, tt := range tests {
+		err := setAnthropicSampling(&anthropic.MessageNewParams{}, tt.req)
+		var paramErr *aisuite.UnsupportedParameterError
+		if !errors.As(err, &paramErr) || paramErr.Provider != Name || paramErr.Parameter != tt.want {
+			t.Errorf("got error %v, want unsupported %s", err, tt.want)
+		}
+	}
+}
+
+func TestSetAnthropicResponseFormat(t *testing.T) {
+	params := anthropic.MessageNewParams{}
+	name, err := setAnthropicResponseFormat(&params, aisuite.ChatCompletionRequest{
+		ResponseFormat: &aisuite.ResponseFormat{Type: aisuite.ResponseFormatTypeJSONObject},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != responseFormatTool {
+		t.Errorf("got tool %q", name)
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"tool_choice":{"name":"json_response","type":"tool"},"tools":[{"description":"Reply with a JSON value matching the input schema.","input_schema":{"type":"object"},"name":"json_response"}]}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+
+	_, err = setAnthropicResponseFormat(&params, aisuite.ChatCompletionRequest{
+		Tools:          []aisuite.Tool{{Name: "get_weather"}},
+		ResponseFormat: &aisuite.ResponseFormat{Type: aisuite.ResponseFormatTypeJSONObject},
+	})
+	var paramErr *aisuite.UnsupportedParameterError
+	if !errors.As(err, &paramErr) {
+		t.Errorf("got error %v, want UnsupportedParameterError", err)
+	}
+}
+
+func TestChatCompletionStreamResponseFormat(t *testing.T) {
+	stream := newTestStream(`event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"json_response","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"answer\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":" 42}"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":15}}
+
+`)
+	stream.formatTool = responseFormatTool
+	resp, err := aisuite.AccumulateStreamFormat(stream, &aisuite.ResponseFormat{Type: aisuite.ResponseFormatTypeJSONObject}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Content != `{"answer": 42}` || string(choice.JSON) != `{"answer": 42}` || len(choice.Message.ToolCalls) != 0 || choice.FinishReason != aisuite.FinishReasonStop {
+		t.Errorf("unexpected choice %+v", choice)
+	}
+}
+
+func TestToError(t *testing.T) {
+	apiErr := &anthropic.Error{}
+	body := `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`
+	if err := json.Unmarshal([]byte(body), apiErr); err != nil {
+		t.Fatal(err)
+	}
+	apiErr.StatusCode = 529
+	apiErr.Response = &http.Response{
+		StatusCode: 529,
+		Header:     http.Header{"Request-Id": {"req_123"}, "Retry-After": {"2"}},
+	}
+	var e *aisuite.Error
+	if err := toError(apiErr); !errors.As(err, &e) {
+		t.Fatalf("got error %v, want *aisuite.Error", err)
+	}
+	if e.Category != aisuite.ErrorCategoryOverloaded || e.StatusCode != 529 || e.Provider != Name ||
+		e.RequestID != "req_123" || e.RetryAfter != 2*time.Second || e.Message != "Overloaded" {
+		t.Errorf("unexpected error %+v", e)
+	}
+
+	tests := map[string]aisuite.ErrorCategory{
+		`{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`: aisuite.ErrorCategoryContextLengthExceeded,
+		`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`:                                   aisuite.ErrorCategoryAuth,
+		`{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your rate limit"}}`:   aisuite.ErrorCategoryRateLimit,
+	}
+	for body, want := range tests {
+		apiErr := &anthropic.Error{}
+		if err := json.Unmarshal([]byte(body), apiErr); err != nil {
+			t.Fatal(err)
+		}
+		if err := toError(apiErr); !errors.As(err, &e) || e.Category != want {
+			t.Errorf("toError(%s) = %v, want category %s", body, err, want)
+		}
+	}
+}
+
+func TestChatCompletionStreamError(t *testing.T) {
+	stream := newTestStream(`event: ping
+data: {"type": "ping"}
+
+event: error
+data: {"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}
+
+`)
+	_, err := stream.Recv()
+	var e *aisuite.Error
+	if !errors.As(err, &e) || e.Category != aisuite.ErrorCategoryOverloaded || e.Message != "Overloaded" {
+		t.Errorf("got error %v, want overloaded *aisuite.Error", err)
+	}
+}
+
+func replay(t *testing.T, path string) *Client {
+	t.Helper()
+	rec, err := cassette.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(providers.Options{Token: "test", HTTPClient: rec.Client()})
+}
+
+var replayRequest = aisuite.ChatCompletionRequest{
+	Model: "claude-3-5-haiku-20241022",
+	Messages: []aisuite.ChatCompletionMessage{
+		{Role: aisuite.RoleSystem, Content: "Be brief."},
+		{Role: aisuite.RoleUser, Content: "Hi"},
+	},
+	MaxTokens: 20,
+}
+
+func TestChatCompletionReplay(t *testing.T) {
+	resp, err := replay(t, "testdata/chat.json").ChatCompletion(context.Background(), replayRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReplay(t, resp, "msg_01XFDUDYJgAACzvnptvVoYEL")
+}
+
+func TestStreamChatCompletionReplay(t *testing.T) {
+	req := replayRequest
+	req.Stream = true
+	stream, err := replay(t, "testdata/stream.json").StreamChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	resp, err := aisuite.AccumulateStream(stream, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReplay(t, resp, "msg_01HCtpWbRmcF3GQWvU3u3Lsw")
+}
+
+func checkReplay(t *testing.T, resp *aisuite.ChatCompletionResponse, id string) {
+	t.Helper()
+	if resp.ID != id || len(resp.Choices) != 1 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Content != "Hello! How can I help?" || choice.FinishReason != aisuite.FinishReasonStop {
+		t.Errorf("unexpected choice %+v", choice)
+	}
+	if want := (aisuite.Usage{PromptTokens: 14, CompletionTokens: 10, TotalTokens: 24}); resp.Usage != want {
+		t.Errorf("got usage %+v, want %+v", resp.Usage, want)
+	}
+}
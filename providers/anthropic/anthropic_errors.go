@@ -0,0 +1,81 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/cpunion/go-aisuite"
+)
+
+// streamErrorPrefix prefixes the error events of streams in the SDK.
+const streamErrorPrefix = "received error while streaming: "
+
+type errorBody struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// toError converts Anthropic SDK errors to *aisuite.Error, other errors are
+// returned unchanged.
+func toError(err error) error {
+	if err == nil {
+		return nil
+	}
+	e := &aisuite.Error{Provider: Name, Err: err}
+	var apiErr *anthropic.Error
+	switch {
+	case errors.As(err, &apiErr):
+		e.StatusCode = apiErr.StatusCode
+		var body errorBody
+		_ = json.Unmarshal([]byte(apiErr.JSON.RawJSON()), &body)
+		e.Message = body.Error.Message
+		e.Category = anthropicErrorCategory(apiErr.StatusCode, body.Error.Type, body.Error.Message)
+		if apiErr.Response != nil {
+			e.RequestID = apiErr.Response.Header.Get("Request-Id")
+			e.RetryAfter = aisuite.ParseRetryAfter(apiErr.Response.Header, time.Now())
+		}
+	case strings.HasPrefix(err.Error(), streamErrorPrefix):
+		var body errorBody
+		if json.Unmarshal([]byte(strings.TrimPrefix(err.Error(), streamErrorPrefix)), &body) != nil {
+			return err
+		}
+		e.Message = body.Error.Message
+		e.Category = anthropicErrorCategory(0, body.Error.Type, body.Error.Message)
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Message = err.Error()
+		e.Category = aisuite.ErrorCategoryTimeout
+	default:
+		return err
+	}
+	return e
+}
+
+func anthropicErrorCategory(status int, typ, message string) aisuite.ErrorCategory {
+	switch typ {
+	case "authentication_error", "permission_error":
+		return aisuite.ErrorCategoryAuth
+	case "rate_limit_error":
+		return aisuite.ErrorCategoryRateLimit
+	case "overloaded_error":
+		return aisuite.ErrorCategoryOverloaded
+	case "api_error":
+		return aisuite.ErrorCategoryServer
+	case "timeout_error":
+		return aisuite.ErrorCategoryTimeout
+	case "invalid_request_error":
+		switch {
+		case strings.Contains(message, "prompt is too long"), strings.Contains(message, "context window"):
+			return aisuite.ErrorCategoryContextLengthExceeded
+		case strings.Contains(message, "credit balance"):
+			return aisuite.ErrorCategoryQuota
+		}
+		return aisuite.ErrorCategoryInvalidRequest
+	}
+	return aisuite.StatusCategory(status)
+}
@@ -0,0 +1,244 @@
+package anthropic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers"
+	"github.com/cpunion/go-aisuite/providers/anthropic/anthropictest"
+)
+
+// newServerClient returns a client of a server replying responses, SDK
+// retries are disabled so scripted errors are returned as is.
+func newServerClient(t *testing.T, responses ...anthropictest.Response) (*anthropictest.Server, *Client) {
+	srv := anthropictest.NewServer(responses...)
+	t.Cleanup(srv.Close)
+	return srv, NewClient(providers.Options{Token: "test", BaseURL: srv.URL + "/anthropic/", DisableSDKRetries: true})
+}
+
+var serverRequest = aisuite.ChatCompletionRequest{
+	Model: "claude-3-5-haiku-20241022",
+	Messages: []aisuite.ChatCompletionMessage{
+		{Role: aisuite.RoleSystem, Content: "Be brief."},
+		{Role: aisuite.RoleUser, Content: "Hi"},
+	},
+	MaxTokens: 20,
+}
+
+func streamServer(t *testing.T, client *Client, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	t.Helper()
+	stream, err := client.StreamChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	return aisuite.AccumulateStream(stream, nil)
+}
+
+func TestServer(t *testing.T) {
+	srv, client := newServerClient(t, anthropictest.Reply("Hello! How can I help?"), anthropictest.Reply("Hello! How can I help?"))
+	want := &aisuite.ChatCompletionResponse{
+		ID:    "msg_1",
+		Model: "claude-3-5-haiku-20241022",
+		Choices: []aisuite.ChatCompletionChoice{{
+			Message:      aisuite.ChatCompletionMessage{Role: aisuite.RoleAssistant, Content: "Hello! How can I help?"},
+			FinishReason: aisuite.FinishReasonStop,
+		}},
+		Usage: aisuite.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+
+	resp, err := client.ChatCompletion(context.Background(), serverRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("got  %+v\nwant %+v", resp, want)
+	}
+	resp, err = streamServer(t, client, serverRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.ID = "msg_2"
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("got  %+v\nwant %+v", resp, want)
+	}
+
+	requests := srv.Requests()
+	if len(requests) != 2 || requests[0].Header.Get("X-Api-Key") != "test" || requests[0].MaxTokens != 20 || requests[0].Stream || !requests[1].Stream {
+		t.Fatalf("unexpected requests %+v", requests)
+	}
+	if system := requests[0].System; len(system) != 1 || system[0].Text != "Be brief." {
+		t.Errorf("unexpected system %+v", system)
+	}
+	if msgs := requests[0].Messages; len(msgs) != 1 || msgs[0].Role != "user" || msgs[0].Content[0].Text != "Hi" {
+		t.Errorf("unexpected messages %+v", msgs)
+	}
+}
+
+func TestServerRequests(t *testing.T) {
+	srv, client := newServerClient(t, anthropictest.Reply("Fine."), anthropictest.Reply("Fine."))
+	req := aisuite.ChatCompletionRequest{
+		Model: "claude-3-5-haiku-20241022",
+		Messages: []aisuite.ChatCompletionMessage{
+			{Role: aisuite.RoleSystem, Content: "Be brief."},
+			{Role: aisuite.RoleUser, Content: "Hi"},
+			{Role: aisuite.RoleAssistant, Content: "Hello!"},
+			{Role: aisuite.RoleUser, Content: "How are you?"},
+		},
+	}
+	if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := streamServer(t, client, req); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both paths send the same conversation.
+	requests := srv.Requests()
+	for _, got := range requests {
+		if len(got.System) != 1 || got.System[0].Text != "Be brief." || got.MaxTokens != defaultMaxTokens {
+			t.Errorf("unexpected request %+v", got)
+		}
+		want := []anthropictest.Message{
+			{Role: "user", Content: anthropictest.Blocks{{Type: "text", Text: "Hi"}}},
+			{Role: "assistant", Content: anthropictest.Blocks{{Type: "text", Text: "Hello!"}}},
+			{Role: "user", Content: anthropictest.Blocks{{Type: "text", Text: "How are you?"}}},
+		}
+		if !reflect.DeepEqual(got.Messages, want) {
+			t.Errorf("got messages %+v\nwant %+v", got.Messages, want)
+		}
+	}
+}
+
+func TestServerToolUse(t *testing.T) {
+	resp := anthropictest.CallTools(
+		anthropictest.ToolUse{Name: "get_weather", Input: `{"city":"Paris"}`},
+		anthropictest.ToolUse{Name: "get_time", Input: `{}`},
+	)
+	resp.Content = append([]anthropictest.Block{{Type: "text", Text: "Let me check."}}, resp.Content...)
+	_, client := newServerClient(t, resp, resp)
+	req := serverRequest
+	req.Tools = []aisuite.Tool{
+		{Name: "get_weather", Parameters: map[string]any{"type": "object"}},
+		{Name: "get_time", Parameters: map[string]any{"type": "object"}},
+	}
+	want := []aisuite.ToolCall{
+		{Index: 0, ID: "toolu_1", Tool: "function", Function: aisuite.FunctionCall{Name: "get_weather", Args: `{"city":"Paris"}`}},
+		{Index: 1, ID: "toolu_2", Tool: "function", Function: aisuite.FunctionCall{Name: "get_time", Args: `{}`}},
+	}
+
+	got, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := got.Choices[0].Message
+	if msg.Content != "Let me check." || got.Choices[0].FinishReason != aisuite.FinishReasonToolCalls || !reflect.DeepEqual(msg.ToolCalls, want) {
+		t.Errorf("unexpected choice %+v", got.Choices[0])
+	}
+	got, err = streamServer(t, client, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = got.Choices[0].Message
+	if msg.Content != "Let me check." || got.Choices[0].FinishReason != aisuite.FinishReasonToolCalls || !reflect.DeepEqual(msg.ToolCalls, want) {
+		t.Errorf("unexpected streamed choice %+v", got.Choices[0])
+	}
+}
+
+func TestServerStreamEvents(t *testing.T) {
+	// The API starts input_json_delta with an empty partial JSON, and may
+	// send pings between any events.
+	_, client := newServerClient(t, anthropictest.Response{Events: []anthropictest.Event{
+		{Type: "message_start", Data: `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-haiku-20241022","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":12,"output_tokens":1}}}`},
+		{Type: "content_block_start", Data: `{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_time","input":{}}}`},
+		{Type: "content_block_delta", Data: `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":""}}`},
+		{Type: "ping", Data: `{"type":"ping"}`},
+		{Type: "content_block_delta", Data: `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{}"}}`},
+		{Type: "content_block_stop", Data: `{"type":"content_block_stop","index":0}`},
+		{Type: "message_delta", Data: `{"type":"message_delta","delta":{"stop_reason":"max_tokens","stop_sequence":null},"usage":{"output_tokens":20}}`},
+		{Type: "message_stop", Data: `{"type":"message_stop"}`},
+	}})
+	got, err := streamServer(t, client, serverRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []aisuite.ToolCall{{ID: "toolu_1", Tool: "function", Function: aisuite.FunctionCall{Name: "get_time", Args: `{}`}}}
+	if choice := got.Choices[0]; choice.FinishReason != aisuite.FinishReasonMaxTokens || !reflect.DeepEqual(choice.Message.ToolCalls, want) {
+		t.Errorf("unexpected choice %+v", choice)
+	}
+	if want := (aisuite.Usage{PromptTokens: 12, CompletionTokens: 20, TotalTokens: 32}); got.Usage != want {
+		t.Errorf("got usage %+v, want %+v", got.Usage, want)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	overloaded := anthropictest.Reply("Hello there")
+	overloaded.StreamError = &anthropictest.ErrorBody{Type: "overloaded_error", Message: "Overloaded"}
+	_, client := newServerClient(t,
+		anthropictest.RateLimited(2*time.Second),
+		anthropictest.RateLimited(2*time.Second),
+		anthropictest.Overloaded(),
+		overloaded,
+	)
+
+	_, err := client.ChatCompletion(context.Background(), serverRequest)
+	checkServerError(t, err, aisuite.ErrorCategoryRateLimit, 429, "req_1", 2*time.Second)
+	_, err = client.StreamChatCompletion(context.Background(), serverRequest)
+	checkServerError(t, err, aisuite.ErrorCategoryRateLimit, 429, "req_2", 2*time.Second)
+	_, err = client.ChatCompletion(context.Background(), serverRequest)
+	checkServerError(t, err, aisuite.ErrorCategoryOverloaded, 529, "req_3", 0)
+
+	var content string
+	stream, err := client.StreamChatCompletion(context.Background(), serverRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	_, err = aisuite.AccumulateStream(stream, func(chunk aisuite.ChatCompletionStreamResponse) {
+		content += chunk.Choices[0].Delta.Content
+	})
+	if content != "Hello there" {
+		t.Errorf("got content %q before the error", content)
+	}
+	checkServerError(t, err, aisuite.ErrorCategoryOverloaded, 0, "", 0)
+}
+
+func TestServerSDKRetries(t *testing.T) {
+	overloaded := anthropictest.Overloaded()
+	overloaded.Header = http.Header{"Retry-After-Ms": {"1"}}
+
+	// The SDK retries by default.
+	srv := anthropictest.NewServer(overloaded, anthropictest.Reply("Hello!"))
+	t.Cleanup(srv.Close)
+	client := NewClient(providers.Options{Token: "test", BaseURL: srv.URL})
+	resp, err := client.ChatCompletion(context.Background(), serverRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Message.Content != "Hello!" || len(srv.Requests()) != 2 {
+		t.Errorf("got %q after %d requests, want the reply after 2", resp.Choices[0].Message.Content, len(srv.Requests()))
+	}
+
+	srv, client = newServerClient(t, overloaded, anthropictest.Reply("Hello!"))
+	_, err = client.ChatCompletion(context.Background(), serverRequest)
+	checkServerError(t, err, aisuite.ErrorCategoryOverloaded, 529, "req_1", time.Millisecond)
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("got %d requests with SDK retries disabled, want 1", n)
+	}
+}
+
+func checkServerError(t *testing.T, err error, category aisuite.ErrorCategory, status int, requestID string, retryAfter time.Duration) {
+	t.Helper()
+	var e *aisuite.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("got error %v, want *aisuite.Error", err)
+	}
+	if e.Category != category || e.StatusCode != status || e.Provider != Name || e.RequestID != requestID || e.RetryAfter != retryAfter {
+		t.Errorf("unexpected error %+v", e)
+	}
+}
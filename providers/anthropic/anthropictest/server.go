@@ -0,0 +1,349 @@
+// Package anthropictest provides a fake Anthropic Messages API server to
+// test the provider offline.
+//
+// The server replies to requests with scripted responses, in order, and
+// records the requests:
+//
+//	srv := anthropictest.NewServer(anthropictest.Reply("Hello!"), anthropictest.Overloaded())
+//	defer srv.Close()
+//	c := anthropic.NewClient(providers.Options{Token: "test", BaseURL: srv.URL})
+package anthropictest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Block is a content block of messages and responses.
+type Block struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+	// ID, Name and Input are set for tool_use blocks.
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// ToolUseID, Content and IsError are set for tool_result blocks.
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+	// Source is set for image and document blocks.
+	Source json.RawMessage `json:"source,omitempty"`
+}
+
+// Blocks are content blocks, they are decoded from a string or an array
+// like the API does.
+type Blocks []Block
+
+func (b *Blocks) UnmarshalJSON(data []byte) error {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		*b = Blocks{{Type: "text", Text: text}}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]Block)(b))
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content Blocks `json:"content"`
+}
+
+// Request is a received Messages API request.
+type Request struct {
+	Model         string            `json:"model"`
+	MaxTokens     int               `json:"max_tokens"`
+	System        Blocks            `json:"system,omitempty"`
+	Messages      []Message         `json:"messages"`
+	Tools         []json.RawMessage `json:"tools,omitempty"`
+	ToolChoice    json.RawMessage   `json:"tool_choice,omitempty"`
+	Stream        bool              `json:"stream,omitempty"`
+	Temperature   *float64          `json:"temperature,omitempty"`
+	TopP          *float64          `json:"top_p,omitempty"`
+	TopK          *int              `json:"top_k,omitempty"`
+	StopSequences []string          `json:"stop_sequences,omitempty"`
+	// Header is the header of the HTTP request.
+	Header http.Header `json:"-"`
+}
+
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// ErrorBody is the error of error responses and stream error events.
+type ErrorBody struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Event is a raw server-sent event.
+type Event struct {
+	Type string
+	Data string
+}
+
+// Response is the scripted reply to a request.
+type Response struct {
+	// StatusCode defaults to 200.
+	StatusCode int
+	Header     http.Header
+	// Error is the error body of non 2xx responses.
+	Error *ErrorBody
+
+	Content    []Block
+	StopReason string
+	Usage      Usage
+
+	// StreamError is sent as an error event after the content blocks of
+	// streams, instead of the message_delta and message_stop events.
+	StreamError *ErrorBody
+	// Events replace the events generated for streams, e.g. to send them
+	// in an unusual order.
+	Events []Event
+}
+
+// Reply returns a response replying text.
+func Reply(text string) Response {
+	return Response{
+		Content:    []Block{{Type: "text", Text: text}},
+		StopReason: "end_turn",
+		Usage:      Usage{InputTokens: 10, OutputTokens: 5},
+	}
+}
+
+// ToolUse is a tool call of CallTools.
+type ToolUse struct {
+	Name  string
+	Input string
+}
+
+// CallTools returns a response calling tools, their IDs are "toolu_1",
+// "toolu_2", etc.
+func CallTools(calls ...ToolUse) Response {
+	resp := Response{StopReason: "tool_use", Usage: Usage{InputTokens: 10, OutputTokens: 5}}
+	for i, call := range calls {
+		resp.Content = append(resp.Content, Block{
+			Type:  "tool_use",
+			ID:    fmt.Sprintf("toolu_%d", i+1),
+			Name:  call.Name,
+			Input: json.RawMessage(call.Input),
+		})
+	}
+	return resp
+}
+
+// Error returns an error response.
+func Error(statusCode int, typ, message string) Response {
+	return Response{StatusCode: statusCode, Error: &ErrorBody{Type: typ, Message: message}}
+}
+
+// RateLimited returns a 429 response asking to retry after retryAfter.
+func RateLimited(retryAfter time.Duration) Response {
+	resp := Error(http.StatusTooManyRequests, "rate_limit_error", "Number of request tokens has exceeded your per-minute rate limit")
+	resp.Header = http.Header{"Retry-After": {strconv.Itoa(int(retryAfter.Seconds()))}}
+	return resp
+}
+
+// Overloaded returns a 529 response.
+func Overloaded() Response {
+	return Error(529, "overloaded_error", "Overloaded")
+}
+
+// Server is an httptest.Server serving /v1/messages under any path prefix.
+// Streams are sent as the full event sequence, with a ping after
+// message_start, a text delta per word and two input_json_delta per tool
+// use block.
+//
+// Requests without API key or version header are rejected, and requests
+// once the script is over fail with a 500.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []Response
+	requests  []Request
+}
+
+func NewServer(responses ...Response) *Server {
+	s := &Server{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Add appends responses to the script.
+func (s *Server) Add(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+// Requests returns the received requests.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/v1/messages") {
+		writeError(w, Error(http.StatusNotFound, "not_found_error", "Not found"))
+		return
+	}
+	if r.Header.Get("X-Api-Key") == "" {
+		writeError(w, Error(http.StatusUnauthorized, "authentication_error", "x-api-key header is required"))
+		return
+	}
+	if r.Header.Get("Anthropic-Version") == "" {
+		writeError(w, Error(http.StatusBadRequest, "invalid_request_error", "anthropic-version: header is required"))
+		return
+	}
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, Error(http.StatusBadRequest, "invalid_request_error", "Invalid JSON body: "+err.Error()))
+		return
+	}
+	req.Header = r.Header.Clone()
+	n, resp, ok := s.next(req)
+	w.Header().Set("Request-Id", fmt.Sprintf("req_%d", n))
+	if !ok {
+		writeError(w, Error(http.StatusInternalServerError, "api_error", "anthropictest: no scripted response left"))
+		return
+	}
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	if resp.Error != nil {
+		writeError(w, resp)
+		return
+	}
+	id := fmt.Sprintf("msg_%d", n)
+	if req.Stream {
+		s.stream(w, req, id, resp)
+		return
+	}
+	writeJSON(w, statusCode(resp), message(id, req.Model, resp.Content, resp.StopReason, resp.Usage))
+}
+
+// next returns the number of the request and its response.
+func (s *Server) next(req Request) (int, Response, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	n := len(s.requests)
+	if len(s.responses) == 0 {
+		return n, Response{}, false
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return n, resp, true
+}
+
+func message(id, model string, content []Block, stopReason string, usage Usage) map[string]any {
+	if content == nil {
+		content = []Block{}
+	}
+	var reason any
+	if stopReason != "" {
+		reason = stopReason
+	}
+	return map[string]any{
+		"id":            id,
+		"type":          "message",
+		"role":          "assistant",
+		"model":         model,
+		"content":       content,
+		"stop_reason":   reason,
+		"stop_sequence": nil,
+		"usage":         usage,
+	}
+}
+
+func (s *Server) stream(w http.ResponseWriter, req Request, id string, resp Response) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(statusCode(resp))
+	send := func(event Event) {
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	if resp.Events != nil {
+		for _, event := range resp.Events {
+			send(event)
+		}
+		return
+	}
+	sendJSON := func(typ string, data map[string]any) {
+		data["type"] = typ
+		encoded, _ := json.Marshal(data)
+		send(Event{Type: typ, Data: string(encoded)})
+	}
+
+	startUsage := resp.Usage
+	startUsage.OutputTokens = 1
+	sendJSON("message_start", map[string]any{"message": message(id, req.Model, nil, "", startUsage)})
+	sendJSON("ping", map[string]any{})
+	for i, block := range resp.Content {
+		switch block.Type {
+		case "tool_use":
+			sendJSON("content_block_start", map[string]any{
+				"index":         i,
+				"content_block": Block{Type: block.Type, ID: block.ID, Name: block.Name, Input: json.RawMessage(`{}`)},
+			})
+			input := string(block.Input)
+			half := len(input) / 2
+			for _, partial := range []string{input[:half], input[half:]} {
+				sendJSON("content_block_delta", map[string]any{
+					"index": i,
+					"delta": map[string]any{"type": "input_json_delta", "partial_json": partial},
+				})
+			}
+		default:
+			sendJSON("content_block_start", map[string]any{
+				"index":         i,
+				"content_block": map[string]any{"type": "text", "text": ""},
+			})
+			for _, word := range strings.SplitAfter(block.Text, " ") {
+				sendJSON("content_block_delta", map[string]any{
+					"index": i,
+					"delta": map[string]any{"type": "text_delta", "text": word},
+				})
+			}
+		}
+		sendJSON("content_block_stop", map[string]any{"index": i})
+	}
+	if resp.StreamError != nil {
+		sendJSON("error", map[string]any{"error": resp.StreamError})
+		return
+	}
+	sendJSON("message_delta", map[string]any{
+		"delta": map[string]any{"stop_reason": resp.StopReason, "stop_sequence": nil},
+		"usage": map[string]any{"output_tokens": resp.Usage.OutputTokens},
+	})
+	sendJSON("message_stop", map[string]any{})
+}
+
+func statusCode(resp Response) int {
+	if resp.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return resp.StatusCode
+}
+
+func writeError(w http.ResponseWriter, resp Response) {
+	writeJSON(w, statusCode(resp), map[string]any{"type": "error", "error": resp.Error})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
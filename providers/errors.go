@@ -0,0 +1,12 @@
+package providers
+
+import "errors"
+
+var (
+	// ErrUnknownProvider is returned for a model whose provider is not registered.
+	ErrUnknownProvider = errors.New("unknown provider")
+	// ErrMissingAPIKey is returned when no API key is given or found in the environment.
+	ErrMissingAPIKey = errors.New("missing API key")
+	// ErrInvalidModel is returned for a model not in the "provider:model" form.
+	ErrInvalidModel = errors.New("invalid model")
+)
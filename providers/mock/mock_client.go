@@ -0,0 +1,294 @@
+// Package mock provides a scripted client to test code using aisuite.Client
+// without network.
+//
+// The client replies to requests with the steps of its script, in order, and
+// records the requests:
+//
+//	c := mock.NewClient(mock.Reply("Hello!"), mock.Fail(err))
+//
+// The mock provider makes registered clients available to client.New:
+//
+//	mock.Register("scripted", c)
+//	resp, err := client.New(nil).ChatCompletion(ctx, aisuite.ChatCompletionRequest{Model: "mock:scripted", ...})
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+)
+
+// ErrNoStep is returned for requests once the script is over.
+var ErrNoStep = errors.New("mock: no scripted step left")
+
+// Step is the scripted reply to a request.
+type Step struct {
+	// Response is returned by ChatCompletion. Unless Chunks are set, it's
+	// streamed as a chunk per choice followed by a chunk with the finish
+	// reasons and usage.
+	Response *aisuite.ChatCompletionResponse
+	// Chunks are streamed by StreamChatCompletion, they are accumulated by
+	// ChatCompletion when Response is nil.
+	Chunks []aisuite.ChatCompletionStreamResponse
+	// Delay is waited before returning the response and before each chunk.
+	Delay time.Duration
+	// Err is returned instead of the response. When streaming, it's returned
+	// by Recv after the chunks if Chunks or Response are set, by
+	// StreamChatCompletion otherwise.
+	Err error
+}
+
+// Reply returns a step replying content.
+func Reply(content string) Step {
+	return Step{Response: &aisuite.ChatCompletionResponse{
+		Choices: []aisuite.ChatCompletionChoice{{
+			Message:      aisuite.ChatCompletionMessage{Role: aisuite.RoleAssistant, Content: content},
+			FinishReason: aisuite.FinishReasonStop,
+		}},
+	}}
+}
+
+// ReplyChunks returns a step streaming a chunk per content delta, separated
+// by delay.
+func ReplyChunks(delay time.Duration, contents ...string) Step {
+	step := Step{Delay: delay}
+	for i, content := range contents {
+		delta := aisuite.ChatCompletionStreamChoiceDelta{Content: content}
+		if i == 0 {
+			delta.Role = aisuite.RoleAssistant
+		}
+		step.Chunks = append(step.Chunks, aisuite.ChatCompletionStreamResponse{
+			Choices: []aisuite.ChatCompletionStreamChoice{{Delta: delta}},
+		})
+	}
+	step.Chunks = append(step.Chunks, aisuite.ChatCompletionStreamResponse{
+		Choices: []aisuite.ChatCompletionStreamChoice{{FinishReason: aisuite.FinishReasonStop}},
+	})
+	return step
+}
+
+// CallTools returns a step calling functions, their IDs are "call_1",
+// "call_2", etc.
+func CallTools(calls ...aisuite.FunctionCall) Step {
+	msg := aisuite.ChatCompletionMessage{Role: aisuite.RoleAssistant}
+	for i, call := range calls {
+		msg.ToolCalls = append(msg.ToolCalls, aisuite.ToolCall{
+			Index:    i,
+			ID:       fmt.Sprintf("call_%d", i+1),
+			Tool:     "function",
+			Function: call,
+		})
+	}
+	return Step{Response: &aisuite.ChatCompletionResponse{
+		Choices: []aisuite.ChatCompletionChoice{{Message: msg, FinishReason: aisuite.FinishReasonToolCalls}},
+	}}
+}
+
+// Fail returns a step failing with err.
+func Fail(err error) Step {
+	return Step{Err: err}
+}
+
+// FailRecv returns a step failing with err, streams are opened and fail on
+// their first Recv.
+func FailRecv(err error) Step {
+	return Step{Chunks: []aisuite.ChatCompletionStreamResponse{}, Err: err}
+}
+
+// Client is an aisuite.Client replying with a script. It's safe for
+// concurrent use, concurrent requests get the steps in arrival order.
+type Client struct {
+	mu       sync.Mutex
+	steps    []Step
+	requests []aisuite.ChatCompletionRequest
+	closed   int
+}
+
+func NewClient(steps ...Step) *Client {
+	return &Client{steps: steps}
+}
+
+// Add appends steps to the script.
+func (c *Client) Add(steps ...Step) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.steps = append(c.steps, steps...)
+}
+
+// Requests returns the received requests.
+func (c *Client) Requests() []aisuite.ChatCompletionRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]aisuite.ChatCompletionRequest(nil), c.requests...)
+}
+
+// Closed returns the number of Close calls on the streams of the client.
+func (c *Client) Closed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// Remaining returns the number of steps left.
+func (c *Client) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.steps)
+}
+
+func (c *Client) next(req aisuite.ChatCompletionRequest) (Step, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	if len(c.steps) == 0 {
+		return Step{}, "", ErrNoStep
+	}
+	step := c.steps[0]
+	c.steps = c.steps[1:]
+	return step, fmt.Sprintf("mock-%d", len(c.requests)), nil
+}
+
+func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	step, id, err := c.next(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := sleep(ctx, step.Delay); err != nil {
+		return nil, err
+	}
+	if step.Err != nil {
+		return nil, step.Err
+	}
+	if step.Response == nil {
+		acc := aisuite.ChatCompletionAccumulator{ResponseFormat: req.ResponseFormat}
+		for _, chunk := range step.Chunks {
+			acc.Add(chunk)
+		}
+		resp, err := acc.Response()
+		if err != nil {
+			return nil, err
+		}
+		return withDefaults(resp, id, req.Model), nil
+	}
+	resp := *step.Response
+	resp.Choices = append([]aisuite.ChatCompletionChoice(nil), resp.Choices...)
+	return withDefaults(&resp, id, req.Model), nil
+}
+
+func withDefaults(resp *aisuite.ChatCompletionResponse, id, model string) *aisuite.ChatCompletionResponse {
+	if resp.ID == "" {
+		resp.ID = id
+	}
+	if resp.Model == "" {
+		resp.Model = model
+	}
+	return resp
+}
+
+func (c *Client) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
+	step, id, err := c.next(req)
+	if err != nil {
+		return nil, err
+	}
+	chunks := step.Chunks
+	if chunks == nil && step.Response != nil {
+		chunks = responseChunks(step.Response)
+	}
+	if chunks == nil && step.Err != nil {
+		if err := sleep(ctx, step.Delay); err != nil {
+			return nil, err
+		}
+		return nil, step.Err
+	}
+	s := &stream{client: c, ctx: ctx, delay: step.Delay, err: step.Err}
+	for _, chunk := range chunks {
+		if chunk.ID == "" {
+			chunk.ID = id
+		}
+		if chunk.Model == "" {
+			chunk.Model = req.Model
+		}
+		s.chunks = append(s.chunks, chunk)
+	}
+	return s, nil
+}
+
+// responseChunks returns a chunk with the message of every choice, then a
+// chunk with the finish reasons and usage.
+func responseChunks(resp *aisuite.ChatCompletionResponse) []aisuite.ChatCompletionStreamResponse {
+	var chunks []aisuite.ChatCompletionStreamResponse
+	usage := resp.Usage
+	last := aisuite.ChatCompletionStreamResponse{ID: resp.ID, Model: resp.Model, Usage: &usage}
+	for _, choice := range resp.Choices {
+		msg := choice.Message
+		chunks = append(chunks, aisuite.ChatCompletionStreamResponse{
+			ID:    resp.ID,
+			Model: resp.Model,
+			Choices: []aisuite.ChatCompletionStreamChoice{{
+				Index: choice.Index,
+				Delta: aisuite.ChatCompletionStreamChoiceDelta{
+					Role:      msg.Role,
+					Content:   msg.Content,
+					ToolCalls: msg.ToolCalls,
+					Refusal:   msg.Refusal,
+				},
+			}},
+		})
+		last.Choices = append(last.Choices, aisuite.ChatCompletionStreamChoice{Index: choice.Index, FinishReason: choice.FinishReason})
+	}
+	return append(chunks, last)
+}
+
+type stream struct {
+	client *Client
+	ctx    context.Context
+	chunks []aisuite.ChatCompletionStreamResponse
+	delay  time.Duration
+	err    error
+	closed bool
+}
+
+func (s *stream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
+	if s.closed {
+		return aisuite.ChatCompletionStreamResponse{}, io.ErrClosedPipe
+	}
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return aisuite.ChatCompletionStreamResponse{}, s.err
+		}
+		return aisuite.ChatCompletionStreamResponse{}, io.EOF
+	}
+	if err := sleep(s.ctx, s.delay); err != nil {
+		return aisuite.ChatCompletionStreamResponse{}, err
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *stream) Close() error {
+	s.client.mu.Lock()
+	s.client.closed++
+	s.client.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,70 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers"
+)
+
+const Name = "mock"
+
+func init() {
+	providers.RegisterProvider(Name, Provider{})
+}
+
+var (
+	mu      sync.RWMutex
+	clients = make(map[string]*Client)
+)
+
+// Register makes "mock:model" resolve to client.
+func Register(model string, client *Client) {
+	mu.Lock()
+	defer mu.Unlock()
+	clients[model] = client
+}
+
+func Unregister(model string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(clients, model)
+}
+
+func lookup(model string) (*Client, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	client, ok := clients[model]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s:%s is not registered", providers.ErrInvalidModel, Name, model)
+	}
+	return client, nil
+}
+
+// Provider dispatches requests to the clients registered by model.
+type Provider struct {
+}
+
+func (p Provider) NewClient(opts providers.Options) (aisuite.Client, error) {
+	return registry{}, nil
+}
+
+type registry struct{}
+
+func (registry) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	client, err := lookup(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	return client.ChatCompletion(ctx, req)
+}
+
+func (registry) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
+	client, err := lookup(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	return client.StreamChatCompletion(ctx, req)
+}
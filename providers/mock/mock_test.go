@@ -0,0 +1,107 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/client"
+)
+
+func TestClient(t *testing.T) {
+	errOverloaded := &aisuite.Error{Category: aisuite.ErrorCategoryOverloaded}
+	call := aisuite.FunctionCall{Name: "get_weather", Args: `{"city":"Paris"}`}
+	c := NewClient(Reply("Hello!"), CallTools(call), Fail(errOverloaded))
+	Register("scripted", c)
+	t.Cleanup(func() { Unregister("scripted") })
+
+	ai := client.New(nil)
+	ctx := context.Background()
+	req := aisuite.ChatCompletionRequest{
+		Model:    "mock:scripted",
+		Messages: []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: "Hi"}},
+	}
+	resp, err := ai.ChatCompletion(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != "mock-1" || resp.Model != "scripted" || resp.Choices[0].Message.Content != "Hello!" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	stream, err := ai.StreamChatCompletion(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	resp, err = aisuite.AccumulateStream(stream, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	want := []aisuite.ToolCall{{ID: "call_1", Tool: "function", Function: call}}
+	if choice.FinishReason != aisuite.FinishReasonToolCalls || !reflect.DeepEqual(choice.Message.ToolCalls, want) {
+		t.Errorf("unexpected choice %+v", choice)
+	}
+
+	if _, err = ai.ChatCompletion(ctx, req); !errors.Is(err, errOverloaded) {
+		t.Errorf("got error %v, want %v", err, errOverloaded)
+	}
+	if _, err = ai.ChatCompletion(ctx, req); !errors.Is(err, ErrNoStep) {
+		t.Errorf("got error %v, want ErrNoStep", err)
+	}
+	if got := c.Requests(); len(got) != 4 || got[0].Model != "scripted" || got[0].Messages[0].Content != "Hi" {
+		t.Errorf("unexpected requests %+v", got)
+	}
+
+	if _, err = ai.ChatCompletion(ctx, aisuite.ChatCompletionRequest{Model: "mock:missing"}); err == nil {
+		t.Error("unregistered model succeeded")
+	}
+}
+
+func TestStream(t *testing.T) {
+	step := ReplyChunks(10*time.Millisecond, "Hel", "lo")
+	step.Err = errors.New("connection lost")
+	c := NewClient(step, ReplyChunks(time.Hour, "Hi"), FailRecv(step.Err))
+
+	stream, err := c.StreamChatCompletion(context.Background(), aisuite.ChatCompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content string
+	start := time.Now()
+	_, err = aisuite.AccumulateStream(stream, func(chunk aisuite.ChatCompletionStreamResponse) {
+		content += chunk.Choices[0].Delta.Content
+	})
+	if err != step.Err || content != "Hello" {
+		t.Errorf("got %q and error %v", content, err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("chunks not delayed, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	stream, err = c.StreamChatCompletion(ctx, aisuite.ChatCompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want deadline exceeded", err)
+	}
+
+	stream, err = c.StreamChatCompletion(context.Background(), aisuite.ChatCompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != step.Err {
+		t.Errorf("got error %v, want %v", err, step.Err)
+	}
+	stream.Close()
+	if c.Closed() != 1 {
+		t.Errorf("got %d streams closed, want 1", c.Closed())
+	}
+}
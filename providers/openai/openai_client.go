@@ -2,7 +2,13 @@ package openai
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"log/slog"
+	"math"
+	"net/http"
+	"slices"
 
 	"github.com/cpunion/go-aisuite"
 	"github.com/cpunion/go-aisuite/providers"
@@ -11,54 +17,85 @@ import (
 
 type Client struct {
 	client *ai.Client
+	name   string
+	// parameters are the supported optional parameters.
+	parameters []string
 }
 
+// Parameters are the optional request parameters supported by OpenAI, by
+// their API name. OpenAI compatible providers may support only some of them,
+// see NewCompatibleClient.
+var Parameters = []string{"seed", "presence_penalty", "frequency_penalty", "logit_bias", "n"}
+
 func NewClient(opts providers.Options) *Client {
+	return NewCompatibleClient(opts, Parameters)
+}
+
+// NewCompatibleClient returns a client of an OpenAI compatible provider
+// supporting parameters, some of Parameters. Requests setting other
+// parameters fail with an *aisuite.UnsupportedParameterError rather than
+// being ignored or rejected by the provider.
+func NewCompatibleClient(opts providers.Options, parameters []string) *Client {
 	config := ai.DefaultConfig(opts.Token)
 	if opts.BaseURL != "" {
 		config.BaseURL = opts.BaseURL
 	}
-	return &Client{client: ai.NewClientWithConfig(config)}
+	if opts.HTTPClient != nil {
+		config.HTTPClient = opts.HTTPClient
+	}
+	config.HTTPClient = headerRecorder{doer: config.HTTPClient}
+	name := opts.Name
+	if name == "" {
+		name = Name
+	}
+	return &Client{client: ai.NewClientWithConfig(config), name: name, parameters: parameters}
 }
 
 func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
-	aiMessages := make([]ai.ChatCompletionMessage, len(req.Messages))
-	for i, msg := range req.Messages {
-		aiMessages[i] = ai.ChatCompletionMessage{
-			Role:    toOpenAIRole(msg.Role),
-			Content: msg.Content,
-		}
-	}
-	chatReq := ai.ChatCompletionRequest{
-		Model:     req.Model,
-		MaxTokens: req.MaxTokens,
-		Stream:    req.Stream,
-		Messages:  aiMessages,
+	chatReq, err := c.toOpenAIRequest(req)
+	if err != nil {
+		return nil, err
 	}
+	chatReq.Stream = req.Stream
+	ctx, header := withHeaderRecorder(ctx)
 	resp, err := c.client.CreateChatCompletion(ctx, chatReq)
 	if err != nil {
-		return nil, err
+		return nil, c.toError(err, *header)
 	}
 	choices := make([]aisuite.ChatCompletionChoice, len(resp.Choices))
 	for i, choice := range resp.Choices {
 		choices[i] = aisuite.ChatCompletionChoice{
+			Index: choice.Index,
 			Message: aisuite.ChatCompletionMessage{
-				Role:    fromOpenAIRole(choice.Message.Role),
-				Content: choice.Message.Content,
+				Role:      fromOpenAIRole(choice.Message.Role),
+				Content:   choice.Message.Content,
+				ToolCalls: fromOpenAIToolCalls(choice.Message.ToolCalls),
+				Refusal:   choice.Message.Refusal,
 			},
+			FinishReason: fromOpenAIFinishReason(choice.FinishReason),
+		}
+		if req.ResponseFormat.IsJSON() {
+			choices[i].JSON, _ = aisuite.ExtractJSON(choice.Message.Content)
 		}
 	}
-	return &aisuite.ChatCompletionResponse{Choices: choices}, nil
+	return &aisuite.ChatCompletionResponse{
+		ID:      resp.ID,
+		Model:   resp.Model,
+		Choices: choices,
+		Usage:   fromOpenAIUsage(resp.Usage),
+	}, nil
 }
 
 type chatCompletionStream struct {
+	client *Client
 	stream *ai.ChatCompletionStream
+	header http.Header
 }
 
 func (c *chatCompletionStream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
 	resp, err := c.stream.Recv()
 	if err != nil {
-		return aisuite.ChatCompletionStreamResponse{}, err
+		return aisuite.ChatCompletionStreamResponse{}, c.client.toError(err, c.header)
 	}
 	choices := make([]aisuite.ChatCompletionStreamChoice, len(resp.Choices))
 	var role aisuite.Role
@@ -74,18 +111,9 @@ func (c *chatCompletionStream) Recv() (aisuite.ChatCompletionStreamResponse, err
 				Args: choice.Delta.FunctionCall.Arguments,
 			}
 		}
-		toolCalls := make([]aisuite.ToolCall, len(choice.Delta.ToolCalls))
-		for j, toolCall := range choice.Delta.ToolCalls {
-			toolCalls[j] = aisuite.ToolCall{
-				ID:   toolCall.ID,
-				Tool: string(toolCall.Type),
-				Function: aisuite.FunctionCall{
-					Name: toolCall.Function.Name,
-					Args: toolCall.Function.Arguments,
-				},
-			}
-		}
+		toolCalls := fromOpenAIToolCalls(choice.Delta.ToolCalls)
 		choices[i] = aisuite.ChatCompletionStreamChoice{
+			Index: choice.Index,
 			Delta: aisuite.ChatCompletionStreamChoiceDelta{
 				Content:      choice.Delta.Content,
 				Role:         role,
@@ -96,7 +124,17 @@ func (c *chatCompletionStream) Recv() (aisuite.ChatCompletionStreamResponse, err
 			FinishReason: fromOpenAIFinishReason(choice.FinishReason),
 		}
 	}
-	return aisuite.ChatCompletionStreamResponse{Choices: choices}, nil
+	var usage *aisuite.Usage
+	if resp.Usage != nil {
+		u := fromOpenAIUsage(*resp.Usage)
+		usage = &u
+	}
+	return aisuite.ChatCompletionStreamResponse{
+		ID:      resp.ID,
+		Model:   resp.Model,
+		Choices: choices,
+		Usage:   usage,
+	}, nil
 }
 
 func (c *chatCompletionStream) Close() error {
@@ -104,24 +142,239 @@ func (c *chatCompletionStream) Close() error {
 }
 
 func (c *Client) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
+	chatReq, err := c.toOpenAIRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	chatReq.Stream = true
+	chatReq.StreamOptions = &ai.StreamOptions{IncludeUsage: true}
+	ctx, header := withHeaderRecorder(ctx)
+	s, err := c.client.CreateChatCompletionStream(ctx, chatReq)
+	if err != nil {
+		return nil, c.toError(err, *header)
+	}
+	return &chatCompletionStream{client: c, stream: s, header: *header}, nil
+}
+
+func (c *Client) toOpenAIRequest(req aisuite.ChatCompletionRequest) (ai.ChatCompletionRequest, error) {
+	if err := c.checkParameters(req); err != nil {
+		return ai.ChatCompletionRequest{}, err
+	}
 	aiMessages := make([]ai.ChatCompletionMessage, len(req.Messages))
 	for i, msg := range req.Messages {
 		aiMessages[i] = ai.ChatCompletionMessage{
-			Role:    toOpenAIRole(msg.Role),
-			Content: msg.Content,
+			Role:       toOpenAIRole(msg.Role),
+			Content:    msg.Content,
+			ToolCalls:  toOpenAIToolCalls(msg.ToolCalls),
+			ToolCallID: msg.ToolCallID,
+		}
+		if len(msg.MultiContent) > 0 {
+			parts, err := c.toOpenAIParts(msg.Parts())
+			if err != nil {
+				return ai.ChatCompletionRequest{}, err
+			}
+			aiMessages[i].Content = ""
+			aiMessages[i].MultiContent = parts
 		}
 	}
-	chatReq := ai.ChatCompletionRequest{
-		Model:     req.Model,
-		Messages:  aiMessages,
-		MaxTokens: req.MaxTokens,
-		Stream:    true,
-	}
-	s, err := c.client.CreateChatCompletionStream(ctx, chatReq)
+	responseFormat, err := toOpenAIResponseFormat(req.ResponseFormat)
 	if err != nil {
-		return nil, err
+		return ai.ChatCompletionRequest{}, err
+	}
+	return ai.ChatCompletionRequest{
+		Model:            req.Model,
+		Messages:         aiMessages,
+		MaxTokens:        req.MaxTokens,
+		Tools:            toOpenAITools(req.Tools),
+		ToolChoice:       toOpenAIToolChoice(req.ToolChoice),
+		Temperature:      toOpenAIFloat(req.Temperature),
+		TopP:             toOpenAIFloat(req.TopP),
+		Stop:             req.Stop,
+		Seed:             req.Seed,
+		PresencePenalty:  toOpenAIFloat(req.PresencePenalty),
+		FrequencyPenalty: toOpenAIFloat(req.FrequencyPenalty),
+		LogitBias:        req.LogitBias,
+		N:                req.N,
+		ResponseFormat:   responseFormat,
+	}, nil
+}
+
+// checkParameters rejects the optional parameters of req the provider
+// doesn't support. TopK isn't supported by OpenAI, and a single choice is
+// always supported.
+func (c *Client) checkParameters(req aisuite.ChatCompletionRequest) error {
+	set := []struct {
+		name string
+		set  bool
+	}{
+		{"top_k", req.TopK != nil},
+		{"seed", req.Seed != nil},
+		{"presence_penalty", req.PresencePenalty != nil},
+		{"frequency_penalty", req.FrequencyPenalty != nil},
+		{"logit_bias", len(req.LogitBias) > 0},
+		{"n", req.N > 1},
+	}
+	for _, param := range set {
+		if param.set && !slices.Contains(c.parameters, param.name) {
+			return &aisuite.UnsupportedParameterError{Provider: c.name, Parameter: param.name}
+		}
+	}
+	return nil
+}
+
+func toOpenAIResponseFormat(format *aisuite.ResponseFormat) (*ai.ChatCompletionResponseFormat, error) {
+	if format == nil {
+		return nil, nil
+	}
+	aiFormat := &ai.ChatCompletionResponseFormat{
+		Type: ai.ChatCompletionResponseFormatType(format.Type),
+	}
+	if format.Type != aisuite.ResponseFormatTypeJSONSchema {
+		return aiFormat, nil
+	}
+	schema, ok := format.Schema.(json.Marshaler)
+	if !ok {
+		data, err := json.Marshal(format.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("openai: invalid response format schema: %w", err)
+		}
+		schema = json.RawMessage(data)
+	}
+	name := format.Name
+	if name == "" {
+		name = "response"
+	}
+	aiFormat.JSONSchema = &ai.ChatCompletionResponseFormatJSONSchema{
+		Name:        name,
+		Description: format.Description,
+		Schema:      schema,
+		Strict:      format.Strict,
+	}
+	return aiFormat, nil
+}
+
+// toOpenAIFloat converts an optional parameter. go-openai omits zero values,
+// so an explicit zero is sent as the smallest float32 as go-openai suggests.
+func toOpenAIFloat(v *float64) float32 {
+	if v == nil {
+		return 0
+	}
+	if *v == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+	return float32(*v)
+}
+
+func (c *Client) toOpenAIParts(parts []aisuite.ContentPart) ([]ai.ChatMessagePart, error) {
+	aiParts := make([]ai.ChatMessagePart, len(parts))
+	for i, part := range parts {
+		switch part.Type {
+		case aisuite.ContentPartTypeText:
+			aiParts[i] = ai.ChatMessagePart{Type: ai.ChatMessagePartTypeText, Text: part.Text}
+		case aisuite.ContentPartTypeImageURL:
+			aiParts[i] = ai.ChatMessagePart{
+				Type:     ai.ChatMessagePartTypeImageURL,
+				ImageURL: &ai.ChatMessageImageURL{URL: part.URL},
+			}
+		case aisuite.ContentPartTypeImage:
+			url := "data:" + part.MediaType + ";base64," + base64.StdEncoding.EncodeToString(part.Data)
+			aiParts[i] = ai.ChatMessagePart{
+				Type:     ai.ChatMessagePartTypeImageURL,
+				ImageURL: &ai.ChatMessageImageURL{URL: url},
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s does not support %q parts", aisuite.ErrUnsupportedContentPart, c.name, part.Type)
+		}
+	}
+	return aiParts, nil
+}
+
+func toOpenAITools(tools []aisuite.Tool) []ai.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+	aiTools := make([]ai.Tool, len(tools))
+	for i, tool := range tools {
+		aiTools[i] = ai.Tool{
+			Type: ai.ToolTypeFunction,
+			Function: &ai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		}
+	}
+	return aiTools
+}
+
+func toOpenAIToolChoice(choice aisuite.ToolChoice) any {
+	switch choice.Mode {
+	case "":
+		return nil
+	case aisuite.ToolChoiceFunction:
+		return ai.ToolChoice{
+			Type:     ai.ToolTypeFunction,
+			Function: ai.ToolFunction{Name: choice.Name},
+		}
+	}
+	// auto, none and required are passed as plain strings.
+	return string(choice.Mode)
+}
+
+func toOpenAIToolCalls(toolCalls []aisuite.ToolCall) []ai.ToolCall {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+	aiToolCalls := make([]ai.ToolCall, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		aiToolCalls[i] = ai.ToolCall{
+			ID:   toolCall.ID,
+			Type: ai.ToolTypeFunction,
+			Function: ai.FunctionCall{
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Args,
+			},
+		}
+	}
+	return aiToolCalls
+}
+
+func fromOpenAIToolCalls(toolCalls []ai.ToolCall) []aisuite.ToolCall {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+	calls := make([]aisuite.ToolCall, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		index := i
+		if toolCall.Index != nil {
+			index = *toolCall.Index
+		}
+		calls[i] = aisuite.ToolCall{
+			Index: index,
+			ID:    toolCall.ID,
+			Tool:  string(toolCall.Type),
+			Function: aisuite.FunctionCall{
+				Name: toolCall.Function.Name,
+				Args: toolCall.Function.Arguments,
+			},
+		}
+	}
+	return calls
+}
+
+func fromOpenAIUsage(usage ai.Usage) aisuite.Usage {
+	u := aisuite.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		u.CachedPromptTokens = usage.PromptTokensDetails.CachedTokens
+	}
+	if usage.CompletionTokensDetails != nil {
+		u.ReasoningTokens = usage.CompletionTokensDetails.ReasoningTokens
 	}
-	return &chatCompletionStream{stream: s}, nil
+	return u
 }
 
 func fromOpenAIFinishReason(reason ai.FinishReason) aisuite.FinishReason {
@@ -132,6 +385,10 @@ func fromOpenAIFinishReason(reason ai.FinishReason) aisuite.FinishReason {
 		return aisuite.FinishReasonStop
 	case ai.FinishReasonLength:
 		return aisuite.FinishReasonMaxTokens
+	case ai.FinishReasonToolCalls, ai.FinishReasonFunctionCall:
+		return aisuite.FinishReasonToolCalls
+	case ai.FinishReasonContentFilter:
+		return aisuite.FinishReasonContentFilter
 	}
 	return aisuite.FinishReason("unknown: " + string(reason))
 }
@@ -144,6 +401,8 @@ func fromOpenAIRole(role string) aisuite.Role {
 		return aisuite.RoleSystem
 	case "assistant":
 		return aisuite.RoleAssistant
+	case "tool":
+		return aisuite.RoleTool
 	}
 	slog.Warn("unknown openai role, should handle this", "role", role)
 	return aisuite.Role(string(role))
@@ -157,6 +416,8 @@ func toOpenAIRole(role aisuite.Role) string {
 		return "system"
 	case aisuite.RoleAssistant:
 		return "assistant"
+	case aisuite.RoleTool:
+		return "tool"
 	}
 	slog.Warn("can't convert aisuite role to openai role, should handle this", "role", role)
 	return string(role)
@@ -0,0 +1,261 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/cassette"
+	"github.com/cpunion/go-aisuite/providers"
+	ai "github.com/sashabaranov/go-openai"
+)
+
+var testClient = NewClient(providers.Options{Token: "test"})
+
+func TestToOpenAIRequestTools(t *testing.T) {
+	req, err := testClient.toOpenAIRequest(aisuite.ChatCompletionRequest{
+		Model: "gpt-4o-mini",
+		Tools: []aisuite.Tool{
+			{
+				Name:        "get_weather",
+				Description: "Get the weather of a city",
+				Parameters: map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"city": map[string]any{"type": "string"}},
+				},
+			},
+		},
+		ToolChoice: aisuite.ToolChoice{Mode: aisuite.ToolChoiceFunction, Name: "get_weather"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Tools []struct {
+			Type     string `json:"type"`
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tools"`
+		ToolChoice struct {
+			Type     string `json:"type"`
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tool_choice"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Type != "function" || got.Tools[0].Function.Name != "get_weather" {
+		t.Errorf("unexpected tools: %s", data)
+	}
+	if got.ToolChoice.Type != "function" || got.ToolChoice.Function.Name != "get_weather" {
+		t.Errorf("unexpected tool choice: %s", data)
+	}
+}
+
+func TestToOpenAIToolChoice(t *testing.T) {
+	tests := []struct {
+		choice aisuite.ToolChoice
+		want   any
+	}{
+		{aisuite.ToolChoice{}, nil},
+		{aisuite.ToolChoice{Mode: aisuite.ToolChoiceAuto}, "auto"},
+		{aisuite.ToolChoice{Mode: aisuite.ToolChoiceNone}, "none"},
+		{aisuite.ToolChoice{Mode: aisuite.ToolChoiceRequired}, "required"},
+	}
+	for _, tt := range tests {
+		if got := toOpenAIToolChoice(tt.choice); got != tt.want {
+			t.Errorf("toOpenAIToolChoice(%v) = %v, want %v", tt.choice, got, tt.want)
+		}
+	}
+}
+
+func TestToOpenAIRequestToolMessages(t *testing.T) {
+	req, err := testClient.toOpenAIRequest(aisuite.ChatCompletionRequest{
+		Messages: []aisuite.ChatCompletionMessage{
+			{Role: aisuite.RoleUser, Content: "Weather in Paris?"},
+			{
+				Role: aisuite.RoleAssistant,
+				ToolCalls: []aisuite.ToolCall{
+					{ID: "call_1", Tool: "function", Function: aisuite.FunctionCall{Name: "get_weather", Args: `{"city":"Paris"}`}},
+				},
+			},
+			{Role: aisuite.RoleTool, ToolCallID: "call_1", Content: "sunny"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assistant, tool := req.Messages[1], req.Messages[2]
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].ID != "call_1" || assistant.ToolCalls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("unexpected assistant message: %+v", assistant)
+	}
+	if tool.Role != "tool" || tool.ToolCallID != "call_1" || tool.Content != "sunny" {
+		t.Errorf("unexpected tool message: %+v", tool)
+	}
+}
+
+func TestToOpenAIRequestMultiContent(t *testing.T) {
+	req, err := testClient.toOpenAIRequest(aisuite.ChatCompletionRequest{
+		Messages: []aisuite.ChatCompletionMessage{
+			{
+				Role:    aisuite.RoleUser,
+				Content: "What is in these images?",
+				MultiContent: []aisuite.ContentPart{
+					aisuite.NewImageURLPart("https://example.com/cat.png"),
+					aisuite.NewImagePart("image/png", []byte("png")),
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := req.Messages[0]
+	if msg.Content != "" || len(msg.MultiContent) != 3 {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if msg.MultiContent[0].Text != "What is in these images?" {
+		t.Errorf("got text part %+v", msg.MultiContent[0])
+	}
+	if got := msg.MultiContent[1].ImageURL.URL; got != "https://example.com/cat.png" {
+		t.Errorf("got image url %q", got)
+	}
+	if got := msg.MultiContent[2].ImageURL.URL; got != "data:image/png;base64,cG5n" {
+		t.Errorf("got inline image url %q", got)
+	}
+
+	_, err = testClient.toOpenAIRequest(aisuite.ChatCompletionRequest{
+		Messages: []aisuite.ChatCompletionMessage{
+			{Role: aisuite.RoleUser, MultiContent: []aisuite.ContentPart{aisuite.NewDocumentPart("application/pdf", []byte("%PDF"))}},
+		},
+	})
+	if !errors.Is(err, aisuite.ErrUnsupportedContentPart) {
+		t.Errorf("got error %v, want ErrUnsupportedContentPart", err)
+	}
+}
+
+func TestFromOpenAIUsage(t *testing.T) {
+	got := fromOpenAIUsage(ai.Usage{
+		PromptTokens:            100,
+		CompletionTokens:        50,
+		TotalTokens:             150,
+		PromptTokensDetails:     &ai.PromptTokensDetails{CachedTokens: 80},
+		CompletionTokensDetails: &ai.CompletionTokensDetails{ReasoningTokens: 30},
+	})
+	want := aisuite.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150, CachedPromptTokens: 80, ReasoningTokens: 30}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestToOpenAIRequestSampling(t *testing.T) {
+	req, err := testClient.toOpenAIRequest(aisuite.ChatCompletionRequest{
+		Temperature:      aisuite.Ptr(0.0),
+		TopP:             aisuite.Ptr(0.5),
+		Stop:             []string{"END"},
+		Seed:             aisuite.Ptr(42),
+		PresencePenalty:  aisuite.Ptr(0.1),
+		FrequencyPenalty: aisuite.Ptr(-0.1),
+		LogitBias:        map[string]int{"1234": -100},
+		N:                2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Temperature == 0 || req.TopP != 0.5 || req.Stop[0] != "END" || *req.Seed != 42 ||
+		req.PresencePenalty != float32(0.1) || req.FrequencyPenalty != float32(-0.1) ||
+		req.LogitBias["1234"] != -100 || req.N != 2 {
+		t.Errorf("unexpected request %+v", req)
+	}
+
+	_, err = testClient.toOpenAIRequest(aisuite.ChatCompletionRequest{TopK: aisuite.Ptr(40)})
+	var paramErr *aisuite.UnsupportedParameterError
+	if !errors.As(err, &paramErr) || paramErr.Provider != "openai" || paramErr.Parameter != "top_k" {
+		t.Errorf("got error %v, want unsupported top_k", err)
+	}
+}
+
+func TestToOpenAIRequestResponseFormat(t *testing.T) {
+	req, err := testClient.toOpenAIRequest(aisuite.ChatCompletionRequest{
+		ResponseFormat: &aisuite.ResponseFormat{
+			Type:   aisuite.ResponseFormatTypeJSONSchema,
+			Schema: map[string]any{"type": "object"},
+			Strict: true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(req.ResponseFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"json_schema","json_schema":{"name":"response","schema":{"type":"object"},"strict":true}}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
+
+func replay(t *testing.T, path string) *Client {
+	t.Helper()
+	rec, err := cassette.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(providers.Options{Token: "test", HTTPClient: rec.Client()})
+}
+
+var replayRequest = aisuite.ChatCompletionRequest{
+	Model: "gpt-4o-mini",
+	Messages: []aisuite.ChatCompletionMessage{
+		{Role: aisuite.RoleSystem, Content: "Be brief."},
+		{Role: aisuite.RoleUser, Content: "Hi"},
+	},
+	MaxTokens: 20,
+}
+
+func TestChatCompletionReplay(t *testing.T) {
+	resp, err := replay(t, "testdata/chat.json").ChatCompletion(context.Background(), replayRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReplay(t, resp, "chatcmpl-AXr1")
+}
+
+func TestStreamChatCompletionReplay(t *testing.T) {
+	req := replayRequest
+	req.Stream = true
+	stream, err := replay(t, "testdata/stream.json").StreamChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	resp, err := aisuite.AccumulateStream(stream, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReplay(t, resp, "chatcmpl-AXr2")
+}
+
+func checkReplay(t *testing.T, resp *aisuite.ChatCompletionResponse, id string) {
+	t.Helper()
+	if resp.ID != id || len(resp.Choices) != 1 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Content != "Hello! How can I help?" || choice.FinishReason != aisuite.FinishReasonStop {
+		t.Errorf("unexpected choice %+v", choice)
+	}
+	if want := (aisuite.Usage{PromptTokens: 18, CompletionTokens: 7, TotalTokens: 25}); resp.Usage != want {
+		t.Errorf("got usage %+v, want %+v", resp.Usage, want)
+	}
+}
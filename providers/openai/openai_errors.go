@@ -0,0 +1,84 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	ai "github.com/sashabaranov/go-openai"
+)
+
+type headerKey struct{}
+
+// headerRecorder records the response headers of a request into the
+// *http.Header stored in its context, go-openai errors don't keep them.
+type headerRecorder struct {
+	doer ai.HTTPDoer
+}
+
+func (d headerRecorder) Do(req *http.Request) (*http.Response, error) {
+	resp, err := d.doer.Do(req)
+	if resp != nil {
+		if header, ok := req.Context().Value(headerKey{}).(*http.Header); ok {
+			*header = resp.Header
+		}
+	}
+	return resp, err
+}
+
+func withHeaderRecorder(ctx context.Context) (context.Context, *http.Header) {
+	header := new(http.Header)
+	return context.WithValue(ctx, headerKey{}, header), header
+}
+
+// toError converts go-openai errors to *aisuite.Error, other errors are
+// returned unchanged.
+func (c *Client) toError(err error, header http.Header) error {
+	if err == nil {
+		return nil
+	}
+	e := &aisuite.Error{Provider: c.name, Err: err}
+	if header != nil {
+		e.RequestID = header.Get("X-Request-Id")
+		e.RetryAfter = aisuite.ParseRetryAfter(header, time.Now())
+	}
+	var apiErr *ai.APIError
+	var reqErr *ai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		e.StatusCode = apiErr.HTTPStatusCode
+		e.Message = apiErr.Message
+		e.Category = openAIErrorCategory(apiErr.HTTPStatusCode, fmt.Sprint(apiErr.Code), apiErr.Type)
+	case errors.As(err, &reqErr):
+		e.StatusCode = reqErr.HTTPStatusCode
+		e.Message = string(reqErr.Body)
+		e.Category = aisuite.StatusCategory(reqErr.HTTPStatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Message = err.Error()
+		e.Category = aisuite.ErrorCategoryTimeout
+	default:
+		return err
+	}
+	return e
+}
+
+func openAIErrorCategory(status int, code, typ string) aisuite.ErrorCategory {
+	switch {
+	case code == "insufficient_quota" || typ == "insufficient_quota":
+		return aisuite.ErrorCategoryQuota
+	case code == "context_length_exceeded" || code == "string_above_max_length":
+		return aisuite.ErrorCategoryContextLengthExceeded
+	case code == "content_filter" || code == "content_policy_violation":
+		return aisuite.ErrorCategoryContentFiltered
+	case code == "rate_limit_exceeded" || strings.Contains(typ, "rate_limit"):
+		return aisuite.ErrorCategoryRateLimit
+	case status == 0 && typ == "server_error":
+		// Errors sent in the middle of a stream have no status code.
+		return aisuite.ErrorCategoryServer
+	}
+	return aisuite.StatusCategory(status)
+}
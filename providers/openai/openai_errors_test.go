@@ -0,0 +1,55 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers"
+)
+
+func TestChatCompletionError(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   aisuite.ErrorCategory
+	}{
+		{429, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`, aisuite.ErrorCategoryRateLimit},
+		{429, `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`, aisuite.ErrorCategoryQuota},
+		{400, `{"error":{"message":"maximum context length","type":"invalid_request_error","code":"context_length_exceeded"}}`, aisuite.ErrorCategoryContextLengthExceeded},
+		{401, `{"error":{"message":"Incorrect API key","type":"invalid_request_error","code":"invalid_api_key"}}`, aisuite.ErrorCategoryAuth},
+		{500, `{"error":{"message":"The server had an error","type":"server_error","code":null}}`, aisuite.ErrorCategoryServer},
+		{502, `<html>Bad Gateway</html>`, aisuite.ErrorCategoryServer},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", "req_123")
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(tt.status)
+			_, _ = w.Write([]byte(tt.body))
+		}))
+		client := NewClient(providers.Options{Name: "groq", Token: "test", BaseURL: server.URL})
+		req := aisuite.ChatCompletionRequest{Model: "gpt-4o-mini"}
+
+		_, err := client.ChatCompletion(context.Background(), req)
+		checkError(t, err, tt.status, tt.want)
+		_, err = client.StreamChatCompletion(context.Background(), req)
+		checkError(t, err, tt.status, tt.want)
+		server.Close()
+	}
+}
+
+func checkError(t *testing.T, err error, status int, want aisuite.ErrorCategory) {
+	t.Helper()
+	var e *aisuite.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("got error %v, want *aisuite.Error", err)
+	}
+	if e.Category != want || e.StatusCode != status || e.Provider != "groq" || e.RequestID != "req_123" || e.RetryAfter != 3*time.Second {
+		t.Errorf("unexpected error %+v", e)
+	}
+}
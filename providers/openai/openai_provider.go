@@ -1,6 +1,7 @@
 package openai
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/cpunion/go-aisuite"
@@ -17,12 +18,12 @@ func init() {
 type Provider struct {
 }
 
-func (p Provider) NewClient(opts providers.Options) aisuite.Client {
+func (p Provider) NewClient(opts providers.Options) (aisuite.Client, error) {
 	if opts.Token == "" {
 		opts.Token = os.Getenv(apiKeyEnvVar)
 		if opts.Token == "" {
-			panic(apiKeyEnvVar + " not found in environment variables")
+			return nil, fmt.Errorf("%w: %s not found in environment variables", providers.ErrMissingAPIKey, apiKeyEnvVar)
 		}
 	}
-	return NewClient(opts)
+	return NewClient(opts), nil
 }
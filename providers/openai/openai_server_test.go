@@ -0,0 +1,151 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers"
+	"github.com/cpunion/go-aisuite/providers/openai/openaitest"
+	ai "github.com/sashabaranov/go-openai"
+)
+
+func TestServer(t *testing.T) {
+	call := ai.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}
+	srv := openaitest.NewServer(
+		openaitest.Reply("Hello! How can I help?"),
+		openaitest.Reply("Hello! How can I help?"),
+		openaitest.CallTools(call),
+		openaitest.CallTools(call),
+		openaitest.RateLimited(2*time.Second),
+		openaitest.RateLimited(2*time.Second),
+	)
+	defer srv.Close()
+	client := NewClient(providers.Options{Name: "groq", Token: "test", BaseURL: srv.URL + "/openai/v1"})
+	ctx := context.Background()
+	req := aisuite.ChatCompletionRequest{
+		Model:    "llama-3.1-8b-instant",
+		Messages: []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: "Hi"}},
+	}
+	want := &aisuite.ChatCompletionResponse{
+		ID:    "chatcmpl-1",
+		Model: "llama-3.1-8b-instant",
+		Choices: []aisuite.ChatCompletionChoice{{
+			Message:      aisuite.ChatCompletionMessage{Role: aisuite.RoleAssistant, Content: "Hello! How can I help?"},
+			FinishReason: aisuite.FinishReasonStop,
+		}},
+		Usage: aisuite.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+
+	resp, err := client.ChatCompletion(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("got  %+v\nwant %+v", resp, want)
+	}
+	resp = streamServer(t, client, req)
+	want.ID = "chatcmpl-2"
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("got  %+v\nwant %+v", resp, want)
+	}
+
+	req.Tools = []aisuite.Tool{{Name: "get_weather", Parameters: map[string]any{"type": "object"}}}
+	wantCalls := []aisuite.ToolCall{{ID: "call_1", Tool: "function", Function: aisuite.FunctionCall{Name: "get_weather", Args: `{"city":"Paris"}`}}}
+	resp, err = client.ChatCompletion(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if choice := resp.Choices[0]; choice.FinishReason != aisuite.FinishReasonToolCalls || !reflect.DeepEqual(choice.Message.ToolCalls, wantCalls) {
+		t.Errorf("unexpected choice %+v", choice)
+	}
+	resp = streamServer(t, client, req)
+	if choice := resp.Choices[0]; choice.FinishReason != aisuite.FinishReasonToolCalls || !reflect.DeepEqual(choice.Message.ToolCalls, wantCalls) {
+		t.Errorf("unexpected streamed choice %+v", choice)
+	}
+
+	_, err = client.ChatCompletion(ctx, req)
+	checkRateLimited(t, err)
+	_, err = client.StreamChatCompletion(ctx, req)
+	checkRateLimited(t, err)
+
+	requests := srv.Requests()
+	if len(requests) != 6 || requests[0].Model != "llama-3.1-8b-instant" || requests[0].Messages[0].Content != "Hi" {
+		t.Fatalf("unexpected requests %+v", requests)
+	}
+	if !requests[1].Stream || requests[1].StreamOptions == nil || !requests[1].StreamOptions.IncludeUsage {
+		t.Errorf("stream request %+v doesn't include usage", requests[1])
+	}
+	if tools := requests[2].Tools; len(tools) != 1 || tools[0].Function.Name != "get_weather" {
+		t.Errorf("unexpected tools %+v", tools)
+	}
+}
+
+func streamServer(t *testing.T, client *Client, req aisuite.ChatCompletionRequest) *aisuite.ChatCompletionResponse {
+	t.Helper()
+	stream, err := client.StreamChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	resp, err := aisuite.AccumulateStream(stream, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func checkRateLimited(t *testing.T, err error) {
+	t.Helper()
+	var e *aisuite.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("got error %v, want *aisuite.Error", err)
+	}
+	if e.Category != aisuite.ErrorCategoryRateLimit || e.StatusCode != 429 || e.Provider != "groq" || e.RetryAfter != 2*time.Second || e.RequestID == "" {
+		t.Errorf("unexpected error %+v", e)
+	}
+}
+
+func TestServerCompatibleParameters(t *testing.T) {
+	srv := openaitest.NewServer(openaitest.Reply("Hi!"))
+	defer srv.Close()
+	client := NewCompatibleClient(providers.Options{Name: "groq", Token: "test", BaseURL: srv.URL + "/openai/v1"}, []string{"seed"})
+	ctx := context.Background()
+	seed := 42
+	req := aisuite.ChatCompletionRequest{
+		Model:    "llama-3.1-8b-instant",
+		Messages: []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: "Hi"}},
+		Seed:     &seed,
+		N:        1,
+	}
+	if _, err := client.ChatCompletion(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	unsupported := map[string]aisuite.ChatCompletionRequest{}
+	logitBias := req
+	logitBias.LogitBias = map[string]int{"1234": -100}
+	unsupported["logit_bias"] = logitBias
+	n := req
+	n.N = 2
+	unsupported["n"] = n
+	for param, req := range unsupported {
+		_, err := client.ChatCompletion(ctx, req)
+		var e *aisuite.UnsupportedParameterError
+		if !errors.As(err, &e) || e.Provider != "groq" || e.Parameter != param {
+			t.Errorf("%s: got error %v, want unsupported parameter", param, err)
+		}
+		_, err = client.StreamChatCompletion(ctx, req)
+		if !errors.As(err, &e) || e.Parameter != param {
+			t.Errorf("%s: got stream error %v, want unsupported parameter", param, err)
+		}
+	}
+
+	requests := srv.Requests()
+	if len(requests) != 1 || requests[0].Seed == nil || *requests[0].Seed != 42 {
+		t.Errorf("unexpected requests %+v", requests)
+	}
+}
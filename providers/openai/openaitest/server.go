@@ -0,0 +1,236 @@
+// Package openaitest provides a fake OpenAI compatible server to test
+// providers offline.
+//
+// The server replies to chat completion requests with scripted responses, in
+// order, and records the requests:
+//
+//	srv := openaitest.NewServer(openaitest.Reply("Hello!"), openaitest.RateLimited(time.Second))
+//	defer srv.Close()
+//	c := openai.NewClient(providers.Options{Token: "test", BaseURL: srv.URL + "/v1"})
+package openaitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ai "github.com/sashabaranov/go-openai"
+)
+
+// Response is the scripted reply to a request.
+type Response struct {
+	// StatusCode defaults to 200.
+	StatusCode int
+	Header     http.Header
+	// Error is the error body of non 2xx responses.
+	Error *ai.APIError
+
+	Message      ai.ChatCompletionMessage
+	FinishReason ai.FinishReason
+	Usage        ai.Usage
+}
+
+// Reply returns a response replying content.
+func Reply(content string) Response {
+	return Response{
+		Message:      ai.ChatCompletionMessage{Role: ai.ChatMessageRoleAssistant, Content: content},
+		FinishReason: ai.FinishReasonStop,
+		Usage:        ai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+}
+
+// CallTools returns a response calling functions, their IDs are "call_1",
+// "call_2", etc.
+func CallTools(calls ...ai.FunctionCall) Response {
+	msg := ai.ChatCompletionMessage{Role: ai.ChatMessageRoleAssistant}
+	for i, call := range calls {
+		msg.ToolCalls = append(msg.ToolCalls, ai.ToolCall{
+			ID:       fmt.Sprintf("call_%d", i+1),
+			Type:     ai.ToolTypeFunction,
+			Function: call,
+		})
+	}
+	return Response{
+		Message:      msg,
+		FinishReason: ai.FinishReasonToolCalls,
+		Usage:        ai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+}
+
+// Error returns an error response.
+func Error(statusCode int, typ, code, message string) Response {
+	apiErr := &ai.APIError{Type: typ, Message: message}
+	if code != "" {
+		apiErr.Code = code
+	}
+	return Response{StatusCode: statusCode, Error: apiErr}
+}
+
+// RateLimited returns a 429 response asking to retry after retryAfter.
+func RateLimited(retryAfter time.Duration) Response {
+	resp := Error(http.StatusTooManyRequests, "requests", "rate_limit_exceeded", "Rate limit reached")
+	resp.Header = http.Header{"Retry-After": {strconv.Itoa(int(retryAfter.Seconds()))}}
+	return resp
+}
+
+// Server is an httptest.Server serving /chat/completions under any path
+// prefix, e.g. "/v1" or "/openai/v1". Streams are sent as a chunk per word
+// of the content and per half of the tool call arguments, followed by a
+// chunk with the finish reason and, if requested, a chunk with the usage.
+//
+// Requests without bearer token are rejected, and requests once the script
+// is over fail with a 500.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []Response
+	requests  []ai.ChatCompletionRequest
+}
+
+func NewServer(responses ...Response) *Server {
+	s := &Server{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Add appends responses to the script.
+func (s *Server) Add(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+// Requests returns the received chat completion requests.
+func (s *Server) Requests() []ai.ChatCompletionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ai.ChatCompletionRequest(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		writeError(w, Error(http.StatusNotFound, "invalid_request_error", "unknown_url", "Unknown request URL: "+r.Method+" "+r.URL.Path))
+		return
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer"); !ok || strings.TrimSpace(token) == "" {
+		writeError(w, Error(http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "Incorrect API key provided"))
+		return
+	}
+	var req ai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, Error(http.StatusBadRequest, "invalid_request_error", "", "Invalid JSON body: "+err.Error()))
+		return
+	}
+	n, resp, ok := s.next(req)
+	w.Header().Set("X-Request-Id", fmt.Sprintf("req_%d", n))
+	if !ok {
+		writeError(w, Error(http.StatusInternalServerError, "server_error", "", "openaitest: no scripted response left"))
+		return
+	}
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	if resp.Error != nil {
+		writeError(w, resp)
+		return
+	}
+	id := fmt.Sprintf("chatcmpl-%d", n)
+	if req.Stream {
+		s.stream(w, req, id, resp)
+		return
+	}
+	writeJSON(w, statusCode(resp), ai.ChatCompletionResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []ai.ChatCompletionChoice{{Message: resp.Message, FinishReason: resp.FinishReason}},
+		Usage:   resp.Usage,
+	})
+}
+
+// next returns the number of the request and its response.
+func (s *Server) next(req ai.ChatCompletionRequest) (int, Response, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	n := len(s.requests)
+	if len(s.responses) == 0 {
+		return n, Response{}, false
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return n, resp, true
+}
+
+func (s *Server) stream(w http.ResponseWriter, req ai.ChatCompletionRequest, id string, resp Response) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(statusCode(resp))
+	send := func(chunk ai.ChatCompletionStreamResponse) {
+		chunk.ID = id
+		chunk.Object = "chat.completion.chunk"
+		chunk.Created = time.Now().Unix()
+		chunk.Model = req.Model
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	delta := func(delta ai.ChatCompletionStreamChoiceDelta) {
+		send(ai.ChatCompletionStreamResponse{Choices: []ai.ChatCompletionStreamChoice{{Delta: delta}}})
+	}
+
+	delta(ai.ChatCompletionStreamChoiceDelta{Role: resp.Message.Role})
+	if content := resp.Message.Content; content != "" {
+		for _, word := range strings.SplitAfter(content, " ") {
+			delta(ai.ChatCompletionStreamChoiceDelta{Content: word})
+		}
+	}
+	for i, call := range resp.Message.ToolCalls {
+		index := i
+		args := call.Function.Arguments
+		half := len(args) / 2
+		delta(ai.ChatCompletionStreamChoiceDelta{ToolCalls: []ai.ToolCall{{
+			Index:    &index,
+			ID:       call.ID,
+			Type:     call.Type,
+			Function: ai.FunctionCall{Name: call.Function.Name, Arguments: args[:half]},
+		}}})
+		delta(ai.ChatCompletionStreamChoiceDelta{ToolCalls: []ai.ToolCall{{
+			Index:    &index,
+			Function: ai.FunctionCall{Arguments: args[half:]},
+		}}})
+	}
+	send(ai.ChatCompletionStreamResponse{Choices: []ai.ChatCompletionStreamChoice{{FinishReason: resp.FinishReason}}})
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		usage := resp.Usage
+		send(ai.ChatCompletionStreamResponse{Choices: []ai.ChatCompletionStreamChoice{}, Usage: &usage})
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func statusCode(resp Response) int {
+	if resp.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return resp.StatusCode
+}
+
+func writeError(w http.ResponseWriter, resp Response) {
+	writeJSON(w, statusCode(resp), struct {
+		Error *ai.APIError `json:"error"`
+	}{resp.Error})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,49 @@
+package openaitest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers"
+	"github.com/cpunion/go-aisuite/providers/gemini"
+	"github.com/cpunion/go-aisuite/providers/groq"
+	"github.com/cpunion/go-aisuite/providers/openai/openaitest"
+	"github.com/cpunion/go-aisuite/providers/sambanova"
+)
+
+func TestCompatibleProviders(t *testing.T) {
+	for _, provider := range []providers.Provider{groq.Provider{}, gemini.Provider{}, sambanova.Provider{}} {
+		srv := openaitest.NewServer(openaitest.Reply("Hello!"))
+		client, err := provider.NewClient(providers.Options{Token: "test", BaseURL: srv.URL + "/v1/"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{
+			Model:    "model",
+			Messages: []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: "Hi"}},
+		})
+		if err != nil {
+			t.Fatalf("%T: %v", provider, err)
+		}
+		if resp.Choices[0].Message.Content != "Hello!" || resp.Usage.TotalTokens != 15 {
+			t.Errorf("%T: unexpected response %+v", provider, resp)
+		}
+		if requests := srv.Requests(); len(requests) != 1 || requests[0].Model != "model" {
+			t.Errorf("%T: unexpected requests %+v", provider, requests)
+		}
+		srv.Close()
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	srv := openaitest.NewServer()
+	defer srv.Close()
+	client, _ := groq.Provider{}.NewClient(providers.Options{Token: " ", BaseURL: srv.URL})
+	_, err := client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{Model: "model"})
+	var e *aisuite.Error
+	if !errors.As(err, &e) || e.Category != aisuite.ErrorCategoryAuth {
+		t.Errorf("got error %v, want auth error", err)
+	}
+}
@@ -1,10 +1,27 @@
 package providers
 
-import "github.com/cpunion/go-aisuite"
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/cpunion/go-aisuite"
+)
 
 type Options struct {
+	// Name is the provider name reported in errors, it is set by providers
+	// sharing a client implementation.
+	Name    string
 	BaseURL string
 	Token   string
+	// HTTPClient sends the requests of the provider, e.g. to record them,
+	// defaults to the client of the provider SDK.
+	HTTPClient *http.Client
+	// DisableSDKRetries turns off the retries of the provider SDK, e.g.
+	// when requests are retried by the retry package. Of the SDKs in use,
+	// only Anthropic's retries, twice by default.
+	DisableSDKRetries bool
 }
 
 type Option func(o Options) Options
@@ -23,8 +40,22 @@ func WithBaseURL(baseURL string) Option {
 	}
 }
 
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o Options) Options {
+		o.HTTPClient = httpClient
+		return o
+	}
+}
+
+func WithDisableSDKRetries(disable bool) Option {
+	return func(o Options) Options {
+		o.DisableSDKRetries = disable
+		return o
+	}
+}
+
 type Provider interface {
-	NewClient(options Options) aisuite.Client
+	NewClient(options Options) (aisuite.Client, error)
 }
 
 var providers = make(map[string]Provider)
@@ -37,3 +68,22 @@ func GetProvider(name string) (Provider, bool) {
 	provider, ok := providers[name]
 	return provider, ok
 }
+
+// Names returns the names of the registered providers, sorted.
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseModel splits a "provider:model" string.
+func ParseModel(model string) (providerName, modelName string, err error) {
+	providerName, modelName, ok := strings.Cut(model, ":")
+	if !ok || providerName == "" || modelName == "" {
+		return "", "", fmt.Errorf("%w: %q, want provider:model", ErrInvalidModel, model)
+	}
+	return providerName, modelName, nil
+}
@@ -0,0 +1,208 @@
+package providertest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers"
+	"github.com/cpunion/go-aisuite/providers/anthropic/anthropictest"
+	"github.com/cpunion/go-aisuite/providers/mock"
+	"github.com/cpunion/go-aisuite/providers/openai/openaitest"
+	ai "github.com/sashabaranov/go-openai"
+)
+
+type openAIBackend struct {
+	server *openaitest.Server
+	client aisuite.Client
+}
+
+// NewOpenAIBackend returns a backend of an OpenAI compatible provider,
+// served by an openaitest.Server.
+func NewOpenAIBackend(t *testing.T, provider providers.Provider) Backend {
+	server := openaitest.NewServer()
+	t.Cleanup(server.Close)
+	client, err := provider.NewClient(providers.Options{Token: "test", BaseURL: server.URL + "/v1/", DisableSDKRetries: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &openAIBackend{server: server, client: client}
+}
+
+func (b *openAIBackend) Client() aisuite.Client {
+	return b.client
+}
+
+func (b *openAIBackend) Reply(reply Reply) {
+	resp := openaitest.Reply(reply.Content)
+	switch reply.FinishReason {
+	case aisuite.FinishReasonMaxTokens:
+		resp.FinishReason = ai.FinishReasonLength
+	case aisuite.FinishReasonToolCalls:
+		resp.FinishReason = ai.FinishReasonToolCalls
+	case aisuite.FinishReasonContentFilter:
+		resp.FinishReason = ai.FinishReasonContentFilter
+	}
+	resp.Usage = ai.Usage{
+		PromptTokens:     reply.Usage.PromptTokens,
+		CompletionTokens: reply.Usage.CompletionTokens,
+		TotalTokens:      reply.Usage.TotalTokens,
+	}
+	b.server.Add(resp)
+}
+
+func (b *openAIBackend) RateLimit() {
+	b.server.Add(openaitest.RateLimited(time.Second))
+}
+
+func (b *openAIBackend) Requests() []Request {
+	var requests []Request
+	for _, req := range b.server.Requests() {
+		r := Request{MaxTokens: req.MaxTokens, Stream: req.Stream}
+		if r.MaxTokens == 0 {
+			r.MaxTokens = req.MaxCompletionTokens
+		}
+		var system []string
+		for _, msg := range req.Messages {
+			text := msg.Content
+			if len(msg.MultiContent) > 0 {
+				var texts []string
+				for _, part := range msg.MultiContent {
+					texts = append(texts, part.Text)
+				}
+				text = strings.Join(texts, "")
+			}
+			if msg.Role == ai.ChatMessageRoleSystem {
+				system = append(system, text)
+				continue
+			}
+			r.Messages = append(r.Messages, Message{Role: aisuite.Role(msg.Role), Content: text})
+		}
+		r.System = joinText(system)
+		requests = append(requests, r)
+	}
+	return requests
+}
+
+type anthropicBackend struct {
+	server *anthropictest.Server
+	client aisuite.Client
+}
+
+// NewAnthropicBackend returns a backend of an Anthropic compatible
+// provider, served by an anthropictest.Server. SDK retries are disabled so
+// scripted errors are returned as is.
+func NewAnthropicBackend(t *testing.T, provider providers.Provider) Backend {
+	server := anthropictest.NewServer()
+	t.Cleanup(server.Close)
+	client, err := provider.NewClient(providers.Options{Token: "test", BaseURL: server.URL, DisableSDKRetries: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &anthropicBackend{server: server, client: client}
+}
+
+func (b *anthropicBackend) Client() aisuite.Client {
+	return b.client
+}
+
+func (b *anthropicBackend) Reply(reply Reply) {
+	resp := anthropictest.Reply(reply.Content)
+	switch reply.FinishReason {
+	case aisuite.FinishReasonMaxTokens:
+		resp.StopReason = "max_tokens"
+	case aisuite.FinishReasonToolCalls:
+		resp.StopReason = "tool_use"
+	}
+	resp.Usage = anthropictest.Usage{InputTokens: reply.Usage.PromptTokens, OutputTokens: reply.Usage.CompletionTokens}
+	b.server.Add(resp)
+}
+
+func (b *anthropicBackend) RateLimit() {
+	b.server.Add(anthropictest.RateLimited(time.Second))
+}
+
+func (b *anthropicBackend) Requests() []Request {
+	var requests []Request
+	for _, req := range b.server.Requests() {
+		r := Request{MaxTokens: req.MaxTokens, Stream: req.Stream, System: blocksText(req.System, "\n")}
+		for _, msg := range req.Messages {
+			r.Messages = append(r.Messages, Message{Role: aisuite.Role(msg.Role), Content: blocksText(msg.Content, "")})
+		}
+		requests = append(requests, r)
+	}
+	return requests
+}
+
+func blocksText(blocks anthropictest.Blocks, sep string) string {
+	var texts []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, sep)
+}
+
+type mockBackend struct {
+	mock   *mock.Client
+	client aisuite.Client
+}
+
+// NewMockBackend returns a backend of the mock provider, the scripted
+// client is registered for Model.
+func NewMockBackend(t *testing.T) Backend {
+	c := mock.NewClient()
+	mock.Register(Model, c)
+	t.Cleanup(func() { mock.Unregister(Model) })
+	client, err := mock.Provider{}.NewClient(providers.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &mockBackend{mock: c, client: client}
+}
+
+func (b *mockBackend) Client() aisuite.Client {
+	return b.client
+}
+
+func (b *mockBackend) Reply(reply Reply) {
+	step := mock.Reply(reply.Content)
+	step.Response.Choices[0].FinishReason = reply.FinishReason
+	step.Response.Usage = reply.Usage
+	b.mock.Add(step)
+}
+
+func (b *mockBackend) RateLimit() {
+	b.mock.Add(mock.Fail(&aisuite.Error{
+		Category:   aisuite.ErrorCategoryRateLimit,
+		StatusCode: 429,
+		Provider:   mock.Name,
+		RetryAfter: time.Second,
+		Message:    "rate limit reached",
+	}))
+}
+
+func (b *mockBackend) Requests() []Request {
+	var requests []Request
+	for _, req := range b.mock.Requests() {
+		r := Request{MaxTokens: req.MaxTokens, Stream: req.Stream}
+		var system []string
+		for _, msg := range req.Messages {
+			var texts []string
+			for _, part := range msg.Parts() {
+				texts = append(texts, part.Text)
+			}
+			text := strings.Join(texts, "")
+			if msg.Role == aisuite.RoleSystem {
+				system = append(system, text)
+				continue
+			}
+			r.Messages = append(r.Messages, Message{Role: msg.Role, Content: text})
+		}
+		r.System = joinText(system)
+		requests = append(requests, r)
+	}
+	return requests
+}
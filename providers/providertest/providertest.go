@@ -0,0 +1,245 @@
+// Package providertest is a conformance suite checking that a provider
+// behaves like the others behind aisuite.Client. The suite drives a client
+// of the provider against a fake backend:
+//
+//	func TestConformance(t *testing.T) {
+//		providertest.Run(t, "groq", func(t *testing.T) providertest.Backend {
+//			return providertest.NewOpenAIBackend(t, groq.Provider{})
+//		})
+//	}
+package providertest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cpunion/go-aisuite"
+)
+
+// Model is the model of the requests sent by the suite.
+const Model = "test-model"
+
+// Backend is a fake backend of a provider, scripted by the suite.
+type Backend interface {
+	// Client returns a client of the provider sending requests to the
+	// backend.
+	Client() aisuite.Client
+	// Reply scripts the reply to the next request.
+	Reply(reply Reply)
+	// RateLimit scripts a rate limit error for the next request.
+	RateLimit()
+	// Requests returns the received requests.
+	Requests() []Request
+}
+
+// Reply is a scripted reply, in provider independent terms.
+type Reply struct {
+	Content      string
+	FinishReason aisuite.FinishReason
+	Usage        aisuite.Usage
+}
+
+// Request is a received request, in provider independent terms.
+type Request struct {
+	// System is the text of the system prompt.
+	System    string
+	Messages  []Message
+	MaxTokens int
+	Stream    bool
+}
+
+// Message is a received message, Content is the text of its parts.
+type Message struct {
+	Role    aisuite.Role
+	Content string
+}
+
+// Run runs the suite for a provider, newBackend is called for every
+// check. Checks are run for ChatCompletion and for StreamChatCompletion.
+func Run(t *testing.T, provider string, newBackend func(t *testing.T) Backend) {
+	for _, stream := range []bool{false, true} {
+		name := "ChatCompletion"
+		if stream {
+			name = "StreamChatCompletion"
+		}
+		t.Run(name, func(t *testing.T) {
+			s := suite{provider: provider, newBackend: newBackend, stream: stream}
+			t.Run("Roles", s.testRoles)
+			t.Run("FinishReasons", s.testFinishReasons)
+			t.Run("MaxTokens", s.testMaxTokens)
+			t.Run("Errors", s.testErrors)
+			t.Run("Cancellation", s.testCancellation)
+		})
+	}
+}
+
+type suite struct {
+	provider   string
+	newBackend func(t *testing.T) Backend
+	stream     bool
+}
+
+var defaultReply = Reply{
+	Content:      "Hello! How can I help?",
+	FinishReason: aisuite.FinishReasonStop,
+	Usage:        aisuite.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+}
+
+func newRequest() aisuite.ChatCompletionRequest {
+	return aisuite.ChatCompletionRequest{
+		Model:    Model,
+		Messages: []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: "Hi"}},
+	}
+}
+
+// complete sends req, streams are accumulated and must end with io.EOF,
+// also on the next Recv.
+func (s suite) complete(ctx context.Context, client aisuite.Client, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	req.Stream = s.stream
+	if !s.stream {
+		return client.ChatCompletion(ctx, req)
+	}
+	stream, err := client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	var acc aisuite.ChatCompletionAccumulator
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		acc.Add(chunk)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		return nil, errors.New("Recv after the end of the stream returned " + errString(err) + ", want io.EOF")
+	}
+	return acc.Response()
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "no error"
+	}
+	return err.Error()
+}
+
+func (s suite) checkReply(t *testing.T, resp *aisuite.ChatCompletionResponse, want Reply) {
+	t.Helper()
+	if len(resp.Choices) != 1 {
+		t.Fatalf("got %d choices, want 1", len(resp.Choices))
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Role != aisuite.RoleAssistant {
+		t.Errorf("got role %q, want %q", choice.Message.Role, aisuite.RoleAssistant)
+	}
+	if choice.Message.Content != want.Content {
+		t.Errorf("got content %q, want %q", choice.Message.Content, want.Content)
+	}
+	if choice.FinishReason != want.FinishReason {
+		t.Errorf("got finish reason %q, want %q", choice.FinishReason, want.FinishReason)
+	}
+	if resp.Usage != want.Usage {
+		t.Errorf("got usage %+v, want %+v", resp.Usage, want.Usage)
+	}
+}
+
+func (s suite) testRoles(t *testing.T) {
+	backend := s.newBackend(t)
+	backend.Reply(defaultReply)
+	req := newRequest()
+	req.Messages = []aisuite.ChatCompletionMessage{
+		{Role: aisuite.RoleSystem, Content: "Be brief."},
+		{Role: aisuite.RoleUser, Content: "Hi"},
+		{Role: aisuite.RoleAssistant, Content: "Hello!"},
+		{Role: aisuite.RoleUser, Content: "How are you?"},
+	}
+	resp, err := s.complete(context.Background(), backend.Client(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.checkReply(t, resp, defaultReply)
+
+	requests := backend.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	got := requests[0]
+	if got.Stream != s.stream {
+		t.Errorf("got stream %v, want %v", got.Stream, s.stream)
+	}
+	if got.System != "Be brief." {
+		t.Errorf("got system prompt %q, want %q", got.System, "Be brief.")
+	}
+	want := []Message{
+		{Role: aisuite.RoleUser, Content: "Hi"},
+		{Role: aisuite.RoleAssistant, Content: "Hello!"},
+		{Role: aisuite.RoleUser, Content: "How are you?"},
+	}
+	if !reflect.DeepEqual(got.Messages, want) {
+		t.Errorf("got messages %+v, want %+v", got.Messages, want)
+	}
+}
+
+func (s suite) testFinishReasons(t *testing.T) {
+	for _, reason := range []aisuite.FinishReason{aisuite.FinishReasonStop, aisuite.FinishReasonMaxTokens} {
+		backend := s.newBackend(t)
+		reply := defaultReply
+		reply.FinishReason = reason
+		backend.Reply(reply)
+		resp, err := s.complete(context.Background(), backend.Client(), newRequest())
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.checkReply(t, resp, reply)
+	}
+}
+
+func (s suite) testMaxTokens(t *testing.T) {
+	backend := s.newBackend(t)
+	backend.Reply(defaultReply)
+	req := newRequest()
+	req.MaxTokens = 7
+	if _, err := s.complete(context.Background(), backend.Client(), req); err != nil {
+		t.Fatal(err)
+	}
+	if requests := backend.Requests(); len(requests) != 1 || requests[0].MaxTokens != 7 {
+		t.Errorf("got requests %+v, want max tokens 7", requests)
+	}
+}
+
+func (s suite) testErrors(t *testing.T) {
+	backend := s.newBackend(t)
+	backend.RateLimit()
+	_, err := s.complete(context.Background(), backend.Client(), newRequest())
+	var e *aisuite.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("got error %v, want *aisuite.Error", err)
+	}
+	if e.Category != aisuite.ErrorCategoryRateLimit || e.Provider != s.provider || !aisuite.IsRetryable(err) {
+		t.Errorf("got error %+v, want retryable rate limit error of %s", e, s.provider)
+	}
+}
+
+func (s suite) testCancellation(t *testing.T) {
+	backend := s.newBackend(t)
+	backend.Reply(defaultReply)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.complete(ctx, backend.Client(), newRequest())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
+
+func joinText(texts []string) string {
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,45 @@
+package providertest_test
+
+import (
+	"testing"
+
+	"github.com/cpunion/go-aisuite/providers"
+	"github.com/cpunion/go-aisuite/providers/anthropic"
+	"github.com/cpunion/go-aisuite/providers/gemini"
+	"github.com/cpunion/go-aisuite/providers/groq"
+	"github.com/cpunion/go-aisuite/providers/mock"
+	"github.com/cpunion/go-aisuite/providers/openai"
+	"github.com/cpunion/go-aisuite/providers/providertest"
+	"github.com/cpunion/go-aisuite/providers/sambanova"
+)
+
+var backends = map[string]func(t *testing.T) providertest.Backend{
+	openai.Name: func(t *testing.T) providertest.Backend {
+		return providertest.NewOpenAIBackend(t, openai.Provider{})
+	},
+	gemini.Name: func(t *testing.T) providertest.Backend {
+		return providertest.NewOpenAIBackend(t, gemini.Provider{})
+	},
+	groq.Name: func(t *testing.T) providertest.Backend {
+		return providertest.NewOpenAIBackend(t, groq.Provider{})
+	},
+	sambanova.Name: func(t *testing.T) providertest.Backend {
+		return providertest.NewOpenAIBackend(t, sambanova.Provider{})
+	},
+	anthropic.Name: func(t *testing.T) providertest.Backend {
+		return providertest.NewAnthropicBackend(t, anthropic.Provider{})
+	},
+	mock.Name: providertest.NewMockBackend,
+}
+
+func TestConformance(t *testing.T) {
+	for _, name := range providers.Names() {
+		t.Run(name, func(t *testing.T) {
+			newBackend, ok := backends[name]
+			if !ok {
+				t.Fatalf("provider %s has no conformance backend", name)
+			}
+			providertest.Run(t, name, newBackend)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package sambanova
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/cpunion/go-aisuite"
@@ -11,6 +12,10 @@ import (
 const Name = "sambanova"
 const apiKeyEnvVar = "SAMBANOVA_API_KEY"
 
+// parameters are the optional OpenAI parameters SambaNova supports, it
+// ignores the others.
+var parameters = []string{}
+
 func init() {
 	providers.RegisterProvider(Name, Provider{})
 }
@@ -18,12 +23,13 @@ func init() {
 type Provider struct {
 }
 
-func (p Provider) NewClient(opts providers.Options) aisuite.Client {
+func (p Provider) NewClient(opts providers.Options) (aisuite.Client, error) {
 	if opts.Token == "" {
 		opts.Token = os.Getenv(apiKeyEnvVar)
 		if opts.Token == "" {
-			panic(apiKeyEnvVar + " not found in environment variables")
+			return nil, fmt.Errorf("%w: %s not found in environment variables", providers.ErrMissingAPIKey, apiKeyEnvVar)
 		}
 	}
-	return openai.NewClient(opts)
+	opts.Name = Name
+	return openai.NewCompatibleClient(opts, parameters), nil
 }
@@ -0,0 +1,310 @@
+// Package ratelimit limits the requests and tokens sent to each model.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers"
+)
+
+// ErrLimitExceeded is wrapped by the *aisuite.Error returned when a request
+// exceeds a limit in fail fast mode, or can't be sent before the context
+// deadline.
+var ErrLimitExceeded = errors.New("ratelimit: limit exceeded")
+
+// Limit is the budget of a provider:model, zero values are unlimited.
+type Limit struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+type Options struct {
+	// Limits are keyed by provider:model, or by provider for a budget shared
+	// by all models of the provider without their own limit.
+	Limits map[string]Limit
+	// FailFast returns an error instead of waiting for the budget.
+	FailFast bool
+	// Estimate estimates the tokens used by a request before it is sent,
+	// the estimate is replaced by the actual usage when it is reported.
+	Estimate func(req aisuite.ChatCompletionRequest) int
+}
+
+type Option func(o Options) Options
+
+func WithLimit(model string, limit Limit) Option {
+	return func(o Options) Options {
+		if o.Limits == nil {
+			o.Limits = make(map[string]Limit)
+		}
+		o.Limits[model] = limit
+		return o
+	}
+}
+
+func WithFailFast(failFast bool) Option {
+	return func(o Options) Options {
+		o.FailFast = failFast
+		return o
+	}
+}
+
+func WithEstimate(estimate func(req aisuite.ChatCompletionRequest) int) Option {
+	return func(o Options) Options {
+		o.Estimate = estimate
+		return o
+	}
+}
+
+// EstimateTokens estimates the tokens of a request as a token per 4
+// characters of text plus MaxTokens.
+func EstimateTokens(req aisuite.ChatCompletionRequest) int {
+	chars := 0
+	for _, msg := range req.Messages {
+		for _, part := range msg.Parts() {
+			chars += len(part.Text)
+		}
+		for _, toolCall := range msg.ToolCalls {
+			chars += len(toolCall.Function.Name) + len(toolCall.Function.Args)
+		}
+	}
+	return chars/4 + req.MaxTokens
+}
+
+// Client limits the requests of a client. Requests to models without limit
+// are sent unchanged.
+type Client struct {
+	client   aisuite.Client
+	opts     Options
+	limiters *limiters
+}
+
+// limiters are the limiters of the limited models and providers.
+type limiters struct {
+	mu sync.Mutex
+	m  map[string]*limiter
+}
+
+func newLimiters() *limiters {
+	return &limiters{m: make(map[string]*limiter)}
+}
+
+// New returns a client limiting the requests of client, it waits for the
+// budget unless in fail fast mode.
+func New(client aisuite.Client, opts ...Option) *Client {
+	o := Options{Estimate: EstimateTokens}
+	for _, opt := range opts {
+		o = opt(o)
+	}
+	return &Client{client: client, opts: o, limiters: newLimiters()}
+}
+
+// Middleware returns a middleware limiting requests, see New. Clients
+// wrapped by the middleware share the budgets.
+func Middleware(opts ...Option) aisuite.Middleware {
+	shared := newLimiters()
+	return func(next aisuite.Client) aisuite.Client {
+		c := New(next, opts...)
+		c.limiters = shared
+		return c
+	}
+}
+
+func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	r, err := c.reserve(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.ChatCompletion(ctx, req)
+	if err == nil {
+		r.reconcile(resp.Usage.TotalTokens)
+	}
+	return resp, err
+}
+
+func (c *Client) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
+	r, err := c.reserve(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := c.client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &usageStream{ChatCompletionStream: stream, reservation: r}, nil
+}
+
+// usageStream reconciles the reservation with the usage of the stream.
+type usageStream struct {
+	aisuite.ChatCompletionStream
+	reservation *reservation
+}
+
+func (s *usageStream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
+	chunk, err := s.ChatCompletionStream.Recv()
+	if err == nil && chunk.Usage != nil {
+		s.reservation.reconcile(chunk.Usage.TotalTokens)
+	}
+	return chunk, err
+}
+
+// limiterFor returns the limiter of model, nil if it's unlimited.
+func (c *Client) limiterFor(model string) *limiter {
+	key := model
+	limit, ok := c.opts.Limits[key]
+	if !ok {
+		provider, _, err := providers.ParseModel(model)
+		if err != nil {
+			return nil
+		}
+		key = provider
+		if limit, ok = c.opts.Limits[key]; !ok {
+			return nil
+		}
+	}
+	c.limiters.mu.Lock()
+	defer c.limiters.mu.Unlock()
+	l, ok := c.limiters.m[key]
+	if !ok {
+		l = newLimiter(limit, time.Now())
+		c.limiters.m[key] = l
+	}
+	return l
+}
+
+// reserve takes the budget of the request, waiting for it unless in fail
+// fast mode.
+func (c *Client) reserve(ctx context.Context, req aisuite.ChatCompletionRequest) (*reservation, error) {
+	l := c.limiterFor(req.Model)
+	if l == nil {
+		return nil, nil
+	}
+	tokens := c.opts.Estimate(req)
+	if limit := l.limit.TokensPerMinute; limit > 0 && tokens > limit {
+		// The request could never be sent otherwise.
+		tokens = limit
+	}
+	wait, ok := l.reserve(tokens, time.Now(), c.maxWait(ctx))
+	if !ok {
+		provider, _, _ := providers.ParseModel(req.Model)
+		return nil, &aisuite.Error{
+			Category:   aisuite.ErrorCategoryRateLimit,
+			Provider:   provider,
+			RetryAfter: wait,
+			Message:    "client-side rate limit exceeded",
+			Err:        ErrLimitExceeded,
+		}
+	}
+	r := &reservation{limiter: l, tokens: tokens}
+	if wait <= 0 {
+		return r, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.cancel(tokens)
+		return nil, ctx.Err()
+	case <-timer.C:
+		return r, nil
+	}
+}
+
+// maxWait returns how long a request may wait for the budget.
+func (c *Client) maxWait(ctx context.Context) time.Duration {
+	if c.opts.FailFast {
+		return 0
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+	return time.Duration(1<<63 - 1)
+}
+
+type reservation struct {
+	limiter *limiter
+	tokens  int
+}
+
+// reconcile replaces the estimated tokens with the actual usage.
+func (r *reservation) reconcile(tokens int) {
+	if r == nil || tokens == 0 {
+		return
+	}
+	r.limiter.adjust(r.tokens - tokens)
+	r.tokens = tokens
+}
+
+// limiter is a pair of token buckets refilled continuously, the balances
+// go negative when requests are reserved ahead of time.
+type limiter struct {
+	mu       sync.Mutex
+	limit    Limit
+	last     time.Time
+	requests float64
+	tokens   float64
+}
+
+func newLimiter(limit Limit, now time.Time) *limiter {
+	return &limiter{
+		limit:    limit,
+		last:     now,
+		requests: float64(limit.RequestsPerMinute),
+		tokens:   float64(limit.TokensPerMinute),
+	}
+}
+
+// reserve takes a request and tokens, and returns how long to wait until
+// the balances are positive. If the wait is longer than maxWait, nothing is
+// taken and false is returned.
+func (l *limiter) reserve(tokens int, now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(now)
+	wait := max(
+		waitFor(l.requests, 1, l.limit.RequestsPerMinute),
+		waitFor(l.tokens, float64(tokens), l.limit.TokensPerMinute),
+	)
+	if wait > maxWait {
+		return wait, false
+	}
+	l.requests--
+	l.tokens -= float64(tokens)
+	return wait, true
+}
+
+func (l *limiter) cancel(tokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests++
+	l.tokens += float64(tokens)
+}
+
+func (l *limiter) adjust(tokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens += float64(tokens)
+}
+
+func (l *limiter) refill(now time.Time) {
+	minutes := now.Sub(l.last).Minutes()
+	l.last = now
+	if limit := float64(l.limit.RequestsPerMinute); limit > 0 {
+		l.requests = min(limit, l.requests+minutes*limit)
+	}
+	if limit := float64(l.limit.TokensPerMinute); limit > 0 {
+		l.tokens = min(limit, l.tokens+minutes*limit)
+	}
+}
+
+// waitFor returns how long until a bucket of balance refilled at perMinute
+// has n available.
+func waitFor(balance, n float64, perMinute int) time.Duration {
+	if perMinute <= 0 || balance >= n {
+		return 0
+	}
+	return time.Duration((n - balance) / float64(perMinute) * float64(time.Minute))
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers/mock"
+)
+
+// replyUsage returns a step replying with a usage of tokens.
+func replyUsage(tokens int) mock.Step {
+	step := mock.Reply("Hello")
+	step.Response.Usage.TotalTokens = tokens
+	return step
+}
+
+func TestFailFast(t *testing.T) {
+	fake := mock.NewClient()
+	for i := 0; i < 5; i++ {
+		fake.Add(mock.Reply("Hello"))
+	}
+	client := New(fake,
+		WithLimit("groq", Limit{RequestsPerMinute: 2}),
+		WithLimit("groq:llama-3.1-8b-instant", Limit{RequestsPerMinute: 1}),
+		WithFailFast(true))
+	ctx := context.Background()
+	for _, model := range []string{"groq:llama-3.1-8b-instant", "groq:mixtral-8x7b-32768", "groq:gemma2-9b-it", "openai:gpt-4o-mini", "openai:gpt-4o-mini"} {
+		if _, err := client.ChatCompletion(ctx, aisuite.ChatCompletionRequest{Model: model}); err != nil {
+			t.Fatalf("%s: %v", model, err)
+		}
+	}
+	for _, model := range []string{"groq:llama-3.1-8b-instant", "groq:mixtral-8x7b-32768"} {
+		_, err := client.ChatCompletion(ctx, aisuite.ChatCompletionRequest{Model: model})
+		var e *aisuite.Error
+		if !errors.As(err, &e) || !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("%s: got error %v, want rate limit error", model, err)
+		}
+		if e.Category != aisuite.ErrorCategoryRateLimit || e.Provider != "groq" || e.RetryAfter < 25*time.Second {
+			t.Errorf("%s: unexpected error %+v", model, e)
+		}
+	}
+	if calls := len(fake.Requests()); calls != 5 {
+		t.Errorf("got %d calls, want 5", calls)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	fake := mock.NewClient(replyUsage(10), replyUsage(10), replyUsage(60))
+	client := New(fake,
+		WithLimit("sambanova:Meta-Llama-3.2-1B-Instruct", Limit{TokensPerMinute: 100}),
+		WithEstimate(func(req aisuite.ChatCompletionRequest) int { return 60 }),
+		WithFailFast(true))
+	req := aisuite.ChatCompletionRequest{Model: "sambanova:Meta-Llama-3.2-1B-Instruct"}
+	// Each request reserves 60 tokens and uses 10, the second one fails
+	// unless the first is reconciled.
+	for i := 0; i < 2; i++ {
+		if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ChatCompletion(context.Background(), req); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("got error %v, want ErrLimitExceeded", err)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	client := New(mock.NewClient(mock.Reply("Hello")), WithLimit("groq", Limit{RequestsPerMinute: 1}))
+	req := aisuite.ChatCompletionRequest{Model: "groq:llama-3.1-8b-instant"}
+	if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.ChatCompletion(ctx, req); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("got error %v, want ErrLimitExceeded", err)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	l := newLimiter(Limit{RequestsPerMinute: 60, TokensPerMinute: 600}, now)
+	forever := time.Duration(1<<63 - 1)
+	tests := []struct {
+		tokens int
+		after  time.Duration
+		want   time.Duration
+	}{
+		{500, 0, 0},
+		{100, 0, 0},
+		// Reserved ahead, the request waits for 300 tokens.
+		{300, 0, 30 * time.Second},
+		// The next one queues behind it.
+		{60, 0, 36 * time.Second},
+		{0, 36 * time.Second, 0},
+	}
+	for i, tt := range tests {
+		now = now.Add(tt.after)
+		wait, ok := l.reserve(tt.tokens, now, forever)
+		if !ok || wait.Round(time.Millisecond) != tt.want {
+			t.Errorf("reserve %d: got wait %v, want %v", i, wait, tt.want)
+		}
+	}
+	if _, ok := l.reserve(100, now, time.Second); ok {
+		t.Error("reserved beyond maxWait")
+	}
+}
@@ -0,0 +1,234 @@
+// Package retry retries failed chat completions with exponential backoff.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+)
+
+type Options struct {
+	// MaxAttempts is the number of attempts including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, it doubles on every
+	// following retry up to MaxDelay, zero MaxDelay doesn't cap it.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter is the fraction of the delay randomly removed from it, so
+	// clients failing together don't retry together.
+	Jitter float64
+	// Categories are the error categories retried, connection failures
+	// are always retried.
+	Categories []aisuite.ErrorCategory
+	// RetryIf overrides Categories to decide whether an error is retried.
+	RetryIf func(err error) bool
+}
+
+type Option func(o Options) Options
+
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(o Options) Options {
+		o.MaxAttempts = maxAttempts
+		return o
+	}
+}
+
+func WithBackoff(baseDelay, maxDelay time.Duration) Option {
+	return func(o Options) Options {
+		o.BaseDelay = baseDelay
+		o.MaxDelay = maxDelay
+		return o
+	}
+}
+
+func WithJitter(jitter float64) Option {
+	return func(o Options) Options {
+		o.Jitter = jitter
+		return o
+	}
+}
+
+func WithCategories(categories ...aisuite.ErrorCategory) Option {
+	return func(o Options) Options {
+		o.Categories = categories
+		return o
+	}
+}
+
+func WithRetryIf(retryIf func(err error) bool) Option {
+	return func(o Options) Options {
+		o.RetryIf = retryIf
+		return o
+	}
+}
+
+// Client retries the requests of a client.
+type Client struct {
+	client aisuite.Client
+	opts   Options
+}
+
+// New returns a client retrying failed requests of client. By default a
+// request is attempted 3 times, waiting 500ms then 1s with 20% jitter, and
+// rate limits, overloaded or failing servers, timeouts and connection
+// failures are retried. A delay asked by the provider with Retry-After is
+// waited instead when longer.
+//
+// The Anthropic SDK also retries, twice by default. Turn that off with
+// client.WithDisableSDKRetries so attempts don't multiply.
+func New(client aisuite.Client, opts ...Option) *Client {
+	o := Options{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		Categories: []aisuite.ErrorCategory{
+			aisuite.ErrorCategoryRateLimit,
+			aisuite.ErrorCategoryOverloaded,
+			aisuite.ErrorCategoryServer,
+			aisuite.ErrorCategoryTimeout,
+		},
+	}
+	for _, opt := range opts {
+		o = opt(o)
+	}
+	return &Client{client: client, opts: o}
+}
+
+// Middleware returns a middleware retrying failed requests, see New.
+func Middleware(opts ...Option) aisuite.Middleware {
+	return func(next aisuite.Client) aisuite.Client {
+		return New(next, opts...)
+	}
+}
+
+func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.client.ChatCompletion(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if err = c.wait(ctx, attempt, err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// StreamChatCompletion retries opening the stream, and receiving its first
+// chunk. Once a chunk is delivered, errors are returned to the caller.
+func (c *Client) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
+	s := &stream{client: c, ctx: ctx, req: req}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *Client) retryable(err error) bool {
+	if c.opts.RetryIf != nil {
+		return c.opts.RetryIf(err)
+	}
+	var e *aisuite.Error
+	if errors.As(err, &e) {
+		return slices.Contains(c.opts.Categories, e.Category)
+	}
+	return aisuite.IsNetworkError(err)
+}
+
+// wait sleeps before retrying the failed attempt. It returns err when it
+// is not retried, and the context error when ctx is done while waiting.
+func (c *Client) wait(ctx context.Context, attempt int, err error) error {
+	if attempt >= c.opts.MaxAttempts || ctx.Err() != nil || !c.retryable(err) {
+		return err
+	}
+	delay := c.delay(attempt)
+	var e *aisuite.Error
+	if errors.As(err, &e) && e.RetryAfter > delay {
+		delay = e.RetryAfter
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		// The retry can't happen before the deadline.
+		return err
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (c *Client) delay(attempt int) time.Duration {
+	delay := c.opts.BaseDelay
+	for i := 1; i < attempt; i++ {
+		if c.opts.MaxDelay > 0 && delay >= c.opts.MaxDelay || delay > math.MaxInt64/2 {
+			break
+		}
+		delay *= 2
+	}
+	if c.opts.MaxDelay > 0 && delay > c.opts.MaxDelay {
+		delay = c.opts.MaxDelay
+	}
+	return delay - time.Duration(c.opts.Jitter*rand.Float64()*float64(delay))
+}
+
+type stream struct {
+	client    *Client
+	ctx       context.Context
+	req       aisuite.ChatCompletionRequest
+	stream    aisuite.ChatCompletionStream
+	attempt   int
+	delivered bool
+	// err is returned by Recv once no replacement stream could be opened.
+	err error
+}
+
+func (s *stream) open() error {
+	for {
+		s.attempt++
+		stream, err := s.client.client.StreamChatCompletion(s.ctx, s.req)
+		if err == nil {
+			s.stream = stream
+			return nil
+		}
+		if err = s.client.wait(s.ctx, s.attempt, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *stream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
+	if s.stream == nil {
+		return aisuite.ChatCompletionStreamResponse{}, s.err
+	}
+	for {
+		chunk, err := s.stream.Recv()
+		if err == nil || s.delivered || err == io.EOF {
+			s.delivered = true
+			return chunk, err
+		}
+		if err = s.client.wait(s.ctx, s.attempt, err); err != nil {
+			return chunk, err
+		}
+		s.stream.Close()
+		s.stream = nil
+		if s.err = s.open(); s.err != nil {
+			return chunk, s.err
+		}
+	}
+}
+
+func (s *stream) Close() error {
+	if s.stream == nil {
+		return nil
+	}
+	return s.stream.Close()
+}
@@ -0,0 +1,170 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers/mock"
+)
+
+// fakeClient fails with errs in order, then replies.
+func fakeClient(errs ...error) *mock.Client {
+	fake := mock.NewClient()
+	for _, err := range errs {
+		fake.Add(mock.Fail(err))
+	}
+	fake.Add(mock.Reply("Hello"))
+	return fake
+}
+
+var (
+	rateLimited = &aisuite.Error{Category: aisuite.ErrorCategoryRateLimit, StatusCode: 429}
+	overloaded  = &aisuite.Error{Category: aisuite.ErrorCategoryOverloaded, StatusCode: 529}
+	badRequest  = &aisuite.Error{Category: aisuite.ErrorCategoryInvalidRequest, StatusCode: 400}
+)
+
+func TestChatCompletion(t *testing.T) {
+	tests := []struct {
+		name    string
+		errs    []error
+		calls   int
+		wantErr error
+	}{
+		{"success", nil, 1, nil},
+		{"retried", []error{rateLimited, overloaded}, 3, nil},
+		{"connection reset", []error{syscall.ECONNRESET}, 2, nil},
+		{"exhausted", []error{rateLimited, rateLimited, overloaded}, 3, overloaded},
+		{"not retried", []error{badRequest}, 1, badRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := fakeClient(tt.errs...)
+			client := New(fake, WithBackoff(time.Millisecond, 10*time.Millisecond))
+			_, err := client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{})
+			if err != tt.wantErr {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if calls := len(fake.Requests()); calls != tt.calls {
+				t.Errorf("got %d calls, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestCategories(t *testing.T) {
+	fake := fakeClient(badRequest)
+	client := New(fake, WithBackoff(time.Millisecond, time.Millisecond),
+		WithCategories(aisuite.ErrorCategoryInvalidRequest))
+	if _, err := client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if calls := len(fake.Requests()); calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	fake := fakeClient(&aisuite.Error{Category: aisuite.ErrorCategoryRateLimit, RetryAfter: 50 * time.Millisecond})
+	client := New(fake, WithBackoff(time.Millisecond, time.Millisecond))
+	start := time.Now()
+	if _, err := client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("retried after %v, want at least 50ms", elapsed)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	tests := []struct {
+		maxDelay time.Duration
+		want     []time.Duration
+	}{
+		{time.Second, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}},
+		{0, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, 1600 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		client := New(nil, WithBackoff(100*time.Millisecond, tt.maxDelay), WithJitter(0))
+		for i, want := range tt.want {
+			if got := client.delay(i + 1); got != want {
+				t.Errorf("max delay %v: got delay %v before retry %d, want %v", tt.maxDelay, got, i+1, want)
+			}
+		}
+	}
+	if got := New(nil, WithBackoff(time.Second, 0), WithJitter(0)).delay(100); got <= 0 {
+		t.Errorf("got delay %v before retry 100, want a positive delay", got)
+	}
+}
+
+func TestContext(t *testing.T) {
+	fake := fakeClient(rateLimited)
+	client := New(fake, WithBackoff(time.Hour, time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.ChatCompletion(ctx, aisuite.ChatCompletionRequest{}); err != rateLimited {
+		t.Errorf("got error %v, want the rate limit error", err)
+	}
+
+	fake = fakeClient(rateLimited)
+	client = New(fake, WithBackoff(time.Hour, time.Hour))
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := client.ChatCompletion(ctx, aisuite.ChatCompletionRequest{}); err != context.Canceled {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
+
+func TestStreamChatCompletion(t *testing.T) {
+	lost := mock.ReplyChunks(0, "Hello", ", world")
+	lost.Err = errors.New("connection lost")
+	fake := mock.NewClient(mock.Fail(overloaded), mock.FailRecv(rateLimited), lost)
+	client := New(fake, WithBackoff(time.Millisecond, time.Millisecond), WithMaxAttempts(5))
+	stream, err := client.StreamChatCompletion(context.Background(), aisuite.ChatCompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content string
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			// Errors after the first chunk are not retried.
+			if err.Error() != "connection lost" {
+				t.Errorf("got error %v, want connection lost", err)
+			}
+			break
+		}
+		content += chunk.Choices[0].Delta.Content
+	}
+	stream.Close()
+	if content != "Hello, world" {
+		t.Errorf("got content %q", content)
+	}
+	if calls := len(fake.Requests()); calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+	if fake.Closed() != 2 {
+		t.Errorf("got %d streams closed, want 2", fake.Closed())
+	}
+}
+
+func TestStreamReopenError(t *testing.T) {
+	fake := mock.NewClient(mock.FailRecv(rateLimited), mock.Fail(badRequest))
+	client := New(fake, WithBackoff(time.Millisecond, time.Millisecond))
+	stream, err := client.StreamChatCompletion(context.Background(), aisuite.ChatCompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = stream.Recv(); err != badRequest {
+			t.Errorf("got error %v, want the bad request error", err)
+		}
+	}
+	stream.Close()
+	if fake.Closed() != 1 {
+		t.Errorf("got %d streams closed, want 1", fake.Closed())
+	}
+}
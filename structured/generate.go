@@ -0,0 +1,121 @@
+// Package structured generates typed values with structured output requests.
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cpunion/go-aisuite"
+)
+
+// ErrInvalidReply is returned when the model still replies with a value not
+// matching the schema after all retries.
+var ErrInvalidReply = errors.New("structured: invalid reply")
+
+type Options struct {
+	// Name is the name of the response format, defaults to "response".
+	Name string
+	// Description tells the model what the value is.
+	Description string
+	// Strict asks the provider to enforce the schema, OpenAI requires all
+	// fields to be required in strict mode.
+	Strict bool
+	// MaxRetries is the number of times the model is asked again with the
+	// validation error after an invalid reply.
+	MaxRetries int
+}
+
+type Option func(o Options) Options
+
+func WithName(name string) Option {
+	return func(o Options) Options {
+		o.Name = name
+		return o
+	}
+}
+
+func WithDescription(description string) Option {
+	return func(o Options) Options {
+		o.Description = description
+		return o
+	}
+}
+
+func WithStrict(strict bool) Option {
+	return func(o Options) Options {
+		o.Strict = strict
+		return o
+	}
+}
+
+func WithMaxRetries(maxRetries int) Option {
+	return func(o Options) Options {
+		o.MaxRetries = maxRetries
+		return o
+	}
+}
+
+// Generate asks the model named by req.Model for a value of type T. The
+// JSON schema of T is sent as the response format, and the reply is
+// validated against it and unmarshaled into T. When the reply is invalid and
+// retries are left, the model is asked again with the validation error.
+func Generate[T any](ctx context.Context, client aisuite.Client, req aisuite.ChatCompletionRequest, opts ...Option) (T, error) {
+	var value T
+	o := Options{Name: "response"}
+	for _, opt := range opts {
+		o = opt(o)
+	}
+	schema, err := SchemaFor[T]()
+	if err != nil {
+		return value, err
+	}
+	req.ResponseFormat = &aisuite.ResponseFormat{
+		Type:        aisuite.ResponseFormatTypeJSONSchema,
+		Name:        o.Name,
+		Description: o.Description,
+		Schema:      schema,
+		Strict:      o.Strict,
+	}
+	messages := append([]aisuite.ChatCompletionMessage(nil), req.Messages...)
+	for attempt := 0; ; attempt++ {
+		req.Messages = messages
+		resp, err := client.ChatCompletion(ctx, req)
+		if err != nil {
+			return value, err
+		}
+		if len(resp.Choices) == 0 {
+			return value, fmt.Errorf("%w: no choices", ErrInvalidReply)
+		}
+		choice := resp.Choices[0]
+		var v T
+		if err = decode(choice, schema, &v); err == nil {
+			return v, nil
+		}
+		if attempt >= o.MaxRetries {
+			return value, fmt.Errorf("%w after %d attempts: %v", ErrInvalidReply, attempt+1, err)
+		}
+		messages = append(messages,
+			aisuite.ChatCompletionMessage{Role: aisuite.RoleAssistant, Content: choice.Message.Content},
+			aisuite.ChatCompletionMessage{
+				Role:    aisuite.RoleUser,
+				Content: fmt.Sprintf("The reply is invalid: %v. Reply again with only a JSON value matching the schema.", err),
+			},
+		)
+	}
+}
+
+func decode(choice aisuite.ChatCompletionChoice, schema *Schema, v any) error {
+	data := choice.JSON
+	if data == nil {
+		var err error
+		if data, err = aisuite.ExtractJSON(choice.Message.Content); err != nil {
+			return err
+		}
+	}
+	if err := schema.Validate(data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,336 @@
+package structured
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON schema derived from Go types.
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Enum        []any              `json:"enum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	// AdditionalProperties is false for structs and the value schema for maps.
+	AdditionalProperties any `json:"additionalProperties,omitempty"`
+	// ContentEncoding is "base64" for []byte.
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+	// Nullable allows null besides Type, it's encoded as a type list, e.g.
+	// ["string","null"], like OpenAI strict mode requires.
+	Nullable bool `json:"-"`
+}
+
+func (s Schema) MarshalJSON() ([]byte, error) {
+	type schema Schema
+	if !s.Nullable || s.Type == "" {
+		return json.Marshal(schema(s))
+	}
+	enum := s.Enum
+	if len(enum) > 0 {
+		enum = append(enum[:len(enum):len(enum)], nil)
+	}
+	return json.Marshal(struct {
+		Type []string `json:"type"`
+		Enum []any    `json:"enum,omitempty"`
+		schema
+	}{[]string{s.Type, "null"}, enum, schema(s)})
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// SchemaFor derives the JSON schema of T.
+//
+// Struct fields are named by their json tag and are required unless tagged
+// omitempty. The jsonschema tag adds constraints, e.g.
+//
+//	Unit string `json:"unit" jsonschema:"description=Temperature unit,enum=celsius,enum=fahrenheit"`
+//
+// The jsonschema_description tag sets descriptions containing commas.
+// Pointers are nullable, []byte is a base64 string like encoding/json
+// encodes it, and json.RawMessage is any value.
+func SchemaFor[T any]() (*Schema, error) {
+	return schemaOf(reflect.TypeFor[T](), nil)
+}
+
+func schemaOf(t reflect.Type, seen []reflect.Type) (*Schema, error) {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	case rawMessageType:
+		return &Schema{}, nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s, err := schemaOf(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		s.Nullable = true
+		return s, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64"}, nil
+		}
+		items, err := schemaOf(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("structured: unsupported map key type %s", t.Key())
+		}
+		values, err := schemaOf(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		for _, s := range seen {
+			if s == t {
+				return nil, fmt.Errorf("structured: recursive type %s", t)
+			}
+		}
+		return structSchema(t, append(seen, t))
+	}
+	return nil, fmt.Errorf("structured: unsupported type %s", t)
+}
+
+func structSchema(t reflect.Type, seen []reflect.Type) (*Schema, error) {
+	s := &Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+	if err := addFields(s, t, seen); err != nil {
+		return nil, err
+	}
+	sort.Strings(s.Required)
+	return s, nil
+}
+
+func addFields(s *Schema, t reflect.Type, seen []reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			// Fields of embedded structs are promoted like encoding/json does.
+			if err := addFields(s, ft, seen); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		prop, err := schemaOf(field.Type, seen)
+		if err != nil {
+			return fmt.Errorf("%w (field %s.%s)", err, t.Name(), field.Name)
+		}
+		required := !strings.Contains(","+opts+",", ",omitempty,")
+		if err := applyTags(prop, field, &required); err != nil {
+			return err
+		}
+		s.Properties[name] = prop
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+func applyTags(s *Schema, field reflect.StructField, required *bool) error {
+	if desc, ok := field.Tag.Lookup("jsonschema_description"); ok {
+		s.Description = desc
+	}
+	tag, ok := field.Tag.Lookup("jsonschema")
+	if !ok {
+		return nil
+	}
+	for _, item := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(item, "=")
+		switch key {
+		case "description":
+			s.Description = value
+		case "required":
+			*required = true
+		case "enum":
+			v, err := enumValue(s.Type, value)
+			if err != nil {
+				return fmt.Errorf("structured: invalid enum %q of field %s: %w", value, field.Name, err)
+			}
+			s.Enum = append(s.Enum, v)
+		case "format":
+			s.Format = value
+		}
+	}
+	return nil
+}
+
+func enumValue(typ, value string) (any, error) {
+	switch typ {
+	case "integer":
+		return strconv.ParseInt(value, 10, 64)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
+
+// Validate checks a JSON value against the schema.
+func (s *Schema) Validate(data json.RawMessage) error {
+	var v any
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	return s.validate("$", v)
+}
+
+func (s *Schema) validate(path string, v any) error {
+	if v == nil && s.Nullable {
+		return nil
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return fmt.Errorf("%s: %v is not one of %v", path, v, s.Enum)
+	}
+	switch s.Type {
+	case "":
+		return nil
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean", path)
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: expected integer", path)
+		}
+		if _, err := n.Int64(); err != nil {
+			return fmt.Errorf("%s: expected integer, got %s", path, n)
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return fmt.Errorf("%s: expected number", path)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string", path)
+		}
+		if s.ContentEncoding == "base64" {
+			if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+				return fmt.Errorf("%s: expected base64 string", path)
+			}
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array", path)
+		}
+		for i, item := range items {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case "object":
+		return s.validateObject(path, v)
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(path string, v any) error {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: expected object", path)
+	}
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := obj[key]
+		if prop, ok := s.Properties[key]; ok {
+			if err := prop.validate(path+"."+key, value); err != nil {
+				return err
+			}
+			continue
+		}
+		switch additional := s.AdditionalProperties.(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: unexpected property %q", path, key)
+			}
+		case *Schema:
+			if err := additional.validate(path+"."+key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func inEnum(enum []any, v any) bool {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return false
+		}
+		v = f
+	}
+	for _, e := range enum {
+		switch e := e.(type) {
+		case int64:
+			if f, ok := v.(float64); ok && f == float64(e) {
+				return true
+			}
+		default:
+			if e == v {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers/mock"
+)
+
+type Base struct {
+	ID string `json:"id"`
+}
+
+type Weather struct {
+	Base
+	City        string            `json:"city" jsonschema:"description=Name of the city"`
+	Temperature float64           `json:"temperature"`
+	Unit        string            `json:"unit" jsonschema:"enum=celsius,enum=fahrenheit"`
+	Days        []int             `json:"days,omitempty"`
+	Notes       map[string]string `json:"notes,omitempty" jsonschema_description:"Notes, by topic"`
+	UpdatedAt   *time.Time        `json:"updated_at,omitempty"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema, err := SchemaFor[Weather]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"object","properties":{` +
+		`"city":{"type":"string","description":"Name of the city"},` +
+		`"days":{"type":"array","items":{"type":"integer"}},` +
+		`"id":{"type":"string"},` +
+		`"notes":{"type":"object","description":"Notes, by topic","additionalProperties":{"type":"string"}},` +
+		`"temperature":{"type":"number"},` +
+		`"unit":{"type":"string","enum":["celsius","fahrenheit"]},` +
+		`"updated_at":{"type":["string","null"],"format":"date-time"}},` +
+		`"required":["city","id","temperature","unit"],"additionalProperties":false}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
+
+type Upload struct {
+	Name  *string         `json:"name"`
+	Kind  *string         `json:"kind" jsonschema:"enum=image,enum=text"`
+	Data  []byte          `json:"data"`
+	Extra json.RawMessage `json:"extra,omitempty"`
+}
+
+func TestSchemaForNullable(t *testing.T) {
+	schema, err := SchemaFor[Upload]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"object","properties":{` +
+		`"data":{"type":"string","contentEncoding":"base64"},` +
+		`"extra":{},` +
+		`"kind":{"type":["string","null"],"enum":["image","text",null]},` +
+		`"name":{"type":["string","null"]}},` +
+		`"required":["data","kind","name"],"additionalProperties":false}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"name":null,"kind":null,"data":"aGk=","extra":{"a":[1]}}`, ""},
+		{`{"name":"cat.png","kind":"image","data":""}`, ""},
+		{`{"name":1,"kind":null,"data":""}`, "$.name: expected string"},
+		{`{"name":null,"kind":"audio","data":""}`, "$.kind: audio is not one of"},
+		{`{"name":null,"kind":null,"data":"not base64!"}`, "$.data: expected base64 string"},
+		{`{"name":null,"kind":null,"data":null}`, "$.data: expected string"},
+	}
+	for _, tt := range tests {
+		err := schema.Validate(json.RawMessage(tt.data))
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("Validate(%s) error: %v", tt.data, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%s) error = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
+
+type Node struct {
+	Children []Node `json:"children"`
+}
+
+func TestSchemaForRecursive(t *testing.T) {
+	if _, err := SchemaFor[Node](); err == nil {
+		t.Error("got nil error for recursive type")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := SchemaFor[Weather]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"id":"1","city":"Paris","temperature":21.5,"unit":"celsius","days":[1,2]}`, ""},
+		{`{"id":"1","city":"Paris","temperature":21.5}`, `missing required property "unit"`},
+		{`{"id":"1","city":"Paris","temperature":21.5,"unit":"kelvin"}`, "$.unit: kelvin is not one of"},
+		{`{"id":"1","city":"Paris","temperature":"hot","unit":"celsius"}`, "$.temperature: expected number"},
+		{`{"id":"1","city":"Paris","temperature":1,"unit":"celsius","days":[1.5]}`, "$.days[0]: expected integer"},
+		{`{"id":"1","city":"Paris","temperature":1,"unit":"celsius","wind":3}`, `unexpected property "wind"`},
+	}
+	for _, tt := range tests {
+		err := schema.Validate(json.RawMessage(tt.data))
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("Validate(%s) error: %v", tt.data, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%s) error = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	client := mock.NewClient(
+		mock.Reply(`{"id":"1","city":"Paris","temperature":21.5,"unit":"kelvin"}`),
+		mock.Reply("```json\n{\"id\":\"1\",\"city\":\"Paris\",\"temperature\":21.5,\"unit\":\"celsius\"}\n```"),
+	)
+	weather, err := Generate[Weather](context.Background(), client, aisuite.ChatCompletionRequest{
+		Model:    "openai:gpt-4o-mini",
+		Messages: []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: "Weather in Paris?"}},
+	}, WithMaxRetries(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weather.City != "Paris" || weather.Unit != "celsius" || weather.Temperature != 21.5 {
+		t.Errorf("got %+v", weather)
+	}
+	requests := client.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	format := requests[0].ResponseFormat
+	if format == nil || format.Type != aisuite.ResponseFormatTypeJSONSchema || format.Name != "response" {
+		t.Errorf("unexpected response format %+v", format)
+	}
+	retry := requests[1].Messages
+	if len(retry) != 3 || retry[1].Role != aisuite.RoleAssistant || !strings.Contains(retry[2].Content, "kelvin is not one of") {
+		t.Errorf("unexpected retry messages %+v", retry)
+	}
+}
+
+func TestGenerateInvalidReply(t *testing.T) {
+	client := mock.NewClient(mock.Reply("I don't know."))
+	_, err := Generate[Weather](context.Background(), client, aisuite.ChatCompletionRequest{})
+	if !errors.Is(err, ErrInvalidReply) {
+		t.Errorf("got error %v, want ErrInvalidReply", err)
+	}
+}
+
+func TestGenerateNull(t *testing.T) {
+	client := mock.NewClient(mock.Reply(`{"name":null,"kind":null,"data":"aGk="}`))
+	upload, err := Generate[Upload](context.Background(), client, aisuite.ChatCompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upload.Name != nil || string(upload.Data) != "hi" || len(client.Requests()) != 1 {
+		t.Errorf("got %+v after %d requests", upload, len(client.Requests()))
+	}
+}
@@ -0,0 +1,273 @@
+// Package tracing traces chat completions with OpenTelemetry, following the
+// GenAI semantic conventions.
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers"
+)
+
+const tracerName = "github.com/cpunion/go-aisuite/tracing"
+
+// GenAI semantic convention attributes, they are experimental and not
+// generated by the semconv packages yet.
+const (
+	operationNameKey      = attribute.Key("gen_ai.operation.name")
+	systemKey             = attribute.Key("gen_ai.system")
+	requestModelKey       = attribute.Key("gen_ai.request.model")
+	requestMaxTokensKey   = attribute.Key("gen_ai.request.max_tokens")
+	requestTemperatureKey = attribute.Key("gen_ai.request.temperature")
+	requestTopPKey        = attribute.Key("gen_ai.request.top_p")
+	requestTopKKey        = attribute.Key("gen_ai.request.top_k")
+	requestStopKey        = attribute.Key("gen_ai.request.stop_sequences")
+	requestSeedKey        = attribute.Key("gen_ai.request.seed")
+	requestPresenceKey    = attribute.Key("gen_ai.request.presence_penalty")
+	requestFrequencyKey   = attribute.Key("gen_ai.request.frequency_penalty")
+	responseIDKey         = attribute.Key("gen_ai.response.id")
+	responseModelKey      = attribute.Key("gen_ai.response.model")
+	finishReasonsKey      = attribute.Key("gen_ai.response.finish_reasons")
+	inputTokensKey        = attribute.Key("gen_ai.usage.input_tokens")
+	outputTokensKey       = attribute.Key("gen_ai.usage.output_tokens")
+	timeToFirstTokenKey   = attribute.Key("gen_ai.server.time_to_first_token")
+	promptKey             = attribute.Key("gen_ai.prompt")
+	completionKey         = attribute.Key("gen_ai.completion")
+	errorTypeKey          = attribute.Key("error.type")
+)
+
+type Options struct {
+	// TracerProvider defaults to the global tracer provider.
+	TracerProvider trace.TracerProvider
+	// CaptureContent records the messages of requests and responses in span
+	// events, they may contain sensitive data.
+	CaptureContent bool
+}
+
+type Option func(o Options) Options
+
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(o Options) Options {
+		o.TracerProvider = tracerProvider
+		return o
+	}
+}
+
+func WithCaptureContent(captureContent bool) Option {
+	return func(o Options) Options {
+		o.CaptureContent = captureContent
+		return o
+	}
+}
+
+// Client traces the requests of a client, a span is recorded for every
+// request. Streams are traced until they end or are closed.
+type Client struct {
+	client aisuite.Client
+	tracer trace.Tracer
+	opts   Options
+}
+
+func New(client aisuite.Client, opts ...Option) *Client {
+	o := Options{}
+	for _, opt := range opts {
+		o = opt(o)
+	}
+	if o.TracerProvider == nil {
+		o.TracerProvider = otel.GetTracerProvider()
+	}
+	return &Client{client: client, tracer: o.TracerProvider.Tracer(tracerName), opts: o}
+}
+
+// Middleware returns a middleware tracing requests, see New.
+func Middleware(opts ...Option) aisuite.Middleware {
+	return func(next aisuite.Client) aisuite.Client {
+		return New(next, opts...)
+	}
+}
+
+func (c *Client) ChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (*aisuite.ChatCompletionResponse, error) {
+	ctx, span := c.start(ctx, req)
+	defer span.End()
+	resp, err := c.client.ChatCompletion(ctx, req)
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+	c.recordResponse(span, resp)
+	return resp, nil
+}
+
+func (c *Client) StreamChatCompletion(ctx context.Context, req aisuite.ChatCompletionRequest) (aisuite.ChatCompletionStream, error) {
+	ctx, span := c.start(ctx, req)
+	stream, err := c.client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		recordError(span, err)
+		span.End()
+		return nil, err
+	}
+	return &tracedStream{client: c, stream: stream, span: span, start: time.Now()}, nil
+}
+
+func (c *Client) start(ctx context.Context, req aisuite.ChatCompletionRequest) (context.Context, trace.Span) {
+	system, model, err := providers.ParseModel(req.Model)
+	if err != nil {
+		// Clients not dispatching by provider take plain model names.
+		system, model = "", req.Model
+	}
+	attrs := []attribute.KeyValue{
+		operationNameKey.String("chat"),
+		requestModelKey.String(model),
+	}
+	if system != "" {
+		attrs = append(attrs, systemKey.String(system))
+	}
+	if req.MaxTokens > 0 {
+		attrs = append(attrs, requestMaxTokensKey.Int(req.MaxTokens))
+	}
+	if req.Temperature != nil {
+		attrs = append(attrs, requestTemperatureKey.Float64(*req.Temperature))
+	}
+	if req.TopP != nil {
+		attrs = append(attrs, requestTopPKey.Float64(*req.TopP))
+	}
+	if req.TopK != nil {
+		attrs = append(attrs, requestTopKKey.Int(*req.TopK))
+	}
+	if len(req.Stop) > 0 {
+		attrs = append(attrs, requestStopKey.StringSlice(req.Stop))
+	}
+	if req.Seed != nil {
+		attrs = append(attrs, requestSeedKey.Int(*req.Seed))
+	}
+	if req.PresencePenalty != nil {
+		attrs = append(attrs, requestPresenceKey.Float64(*req.PresencePenalty))
+	}
+	if req.FrequencyPenalty != nil {
+		attrs = append(attrs, requestFrequencyKey.Float64(*req.FrequencyPenalty))
+	}
+	ctx, span := c.tracer.Start(ctx, "chat "+model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+	if c.opts.CaptureContent {
+		span.AddEvent("gen_ai.content.prompt", trace.WithAttributes(promptKey.String(promptContent(req.Messages))))
+	}
+	return ctx, span
+}
+
+func (c *Client) recordResponse(span trace.Span, resp *aisuite.ChatCompletionResponse) {
+	finishReasons := make([]string, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		finishReasons[i] = string(choice.FinishReason)
+	}
+	span.SetAttributes(
+		responseIDKey.String(resp.ID),
+		responseModelKey.String(resp.Model),
+		finishReasonsKey.StringSlice(finishReasons),
+		inputTokensKey.Int(resp.Usage.PromptTokens),
+		outputTokensKey.Int(resp.Usage.CompletionTokens),
+	)
+	if c.opts.CaptureContent {
+		span.AddEvent("gen_ai.content.completion", trace.WithAttributes(completionKey.String(completionContent(resp.Choices))))
+	}
+}
+
+func recordError(span trace.Span, err error) {
+	errorType := fmt.Sprintf("%T", err)
+	var e *aisuite.Error
+	if errors.As(err, &e) {
+		errorType = string(e.Category)
+	}
+	span.SetAttributes(errorTypeKey.String(errorType))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+type tracedStream struct {
+	client      *Client
+	stream      aisuite.ChatCompletionStream
+	span        trace.Span
+	start       time.Time
+	accumulator aisuite.ChatCompletionAccumulator
+	received    bool
+	ended       bool
+}
+
+func (s *tracedStream) Recv() (aisuite.ChatCompletionStreamResponse, error) {
+	chunk, err := s.stream.Recv()
+	if err != nil {
+		if s.ended {
+			return chunk, err
+		}
+		if err == io.EOF {
+			resp := s.accumulator.Snapshot()
+			s.client.recordResponse(s.span, &resp)
+		} else {
+			recordError(s.span, err)
+		}
+		s.end()
+		return chunk, err
+	}
+	if !s.received {
+		s.received = true
+		s.span.AddEvent("gen_ai.first_token", trace.WithAttributes(
+			timeToFirstTokenKey.Float64(time.Since(s.start).Seconds())))
+	}
+	s.accumulator.Add(chunk)
+	return chunk, nil
+}
+
+// Close ends the span of streams closed before the end.
+func (s *tracedStream) Close() error {
+	if !s.ended {
+		resp := s.accumulator.Snapshot()
+		s.client.recordResponse(s.span, &resp)
+		s.end()
+	}
+	return s.stream.Close()
+}
+
+func (s *tracedStream) end() {
+	s.ended = true
+	s.span.End()
+}
+
+type message struct {
+	Role      aisuite.Role       `json:"role"`
+	Content   string             `json:"content,omitempty"`
+	ToolCalls []aisuite.ToolCall `json:"tool_calls,omitempty"`
+}
+
+// promptContent returns the text content of messages as JSON, other
+// content parts are omitted.
+func promptContent(messages []aisuite.ChatCompletionMessage) string {
+	msgs := make([]message, len(messages))
+	for i, msg := range messages {
+		content := ""
+		for _, part := range msg.Parts() {
+			content += part.Text
+		}
+		msgs[i] = message{Role: msg.Role, Content: content, ToolCalls: msg.ToolCalls}
+	}
+	data, _ := json.Marshal(msgs)
+	return string(data)
+}
+
+func completionContent(choices []aisuite.ChatCompletionChoice) string {
+	msgs := make([]message, len(choices))
+	for i, choice := range choices {
+		msgs[i] = message{Role: choice.Message.Role, Content: choice.Message.Content, ToolCalls: choice.Message.ToolCalls}
+	}
+	data, _ := json.Marshal(msgs)
+	return string(data)
+}
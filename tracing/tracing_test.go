@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/cpunion/go-aisuite"
+	"github.com/cpunion/go-aisuite/providers/mock"
+)
+
+func reply() mock.Step {
+	step := mock.Reply("Hello!")
+	step.Response.ID = "chatcmpl-1"
+	step.Response.Model = "gpt-4o-mini-2024-07-18"
+	step.Response.Usage = aisuite.Usage{PromptTokens: 9, CompletionTokens: 3, TotalTokens: 12}
+	return step
+}
+
+func replyChunks() mock.Step {
+	return mock.Step{Chunks: []aisuite.ChatCompletionStreamResponse{
+		{ID: "msg_1", Model: "claude-3-5-haiku-20241022", Choices: []aisuite.ChatCompletionStreamChoice{
+			{Delta: aisuite.ChatCompletionStreamChoiceDelta{Role: aisuite.RoleAssistant, Content: "Hel"}},
+		}},
+		{ID: "msg_1", Model: "claude-3-5-haiku-20241022", Choices: []aisuite.ChatCompletionStreamChoice{
+			{Delta: aisuite.ChatCompletionStreamChoiceDelta{Content: "lo!"}, FinishReason: aisuite.FinishReasonMaxTokens},
+		}, Usage: &aisuite.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}},
+	}}
+}
+
+func newTestClient(client aisuite.Client, opts ...Option) (*Client, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return New(client, append(opts, WithTracerProvider(provider))...), exporter
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestChatCompletion(t *testing.T) {
+	client, exporter := newTestClient(mock.NewClient(reply()))
+	_, err := client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{
+		Model:       "openai:gpt-4o-mini",
+		Messages:    []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: "Hi"}},
+		MaxTokens:   100,
+		Temperature: aisuite.Ptr(0.5),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "chat gpt-4o-mini" {
+		t.Errorf("got span name %q", span.Name)
+	}
+	attrs := attributes(span)
+	if attrs[systemKey].AsString() != "openai" ||
+		attrs[requestModelKey].AsString() != "gpt-4o-mini" ||
+		attrs[requestMaxTokensKey].AsInt64() != 100 ||
+		attrs[requestTemperatureKey].AsFloat64() != 0.5 ||
+		attrs[responseIDKey].AsString() != "chatcmpl-1" ||
+		attrs[responseModelKey].AsString() != "gpt-4o-mini-2024-07-18" ||
+		attrs[finishReasonsKey].AsStringSlice()[0] != "stop" ||
+		attrs[inputTokensKey].AsInt64() != 9 ||
+		attrs[outputTokensKey].AsInt64() != 3 {
+		t.Errorf("unexpected attributes %v", span.Attributes)
+	}
+	if len(span.Events) != 0 {
+		t.Errorf("content captured without opt-in: %v", span.Events)
+	}
+}
+
+func TestChatCompletionError(t *testing.T) {
+	client, exporter := newTestClient(mock.NewClient(mock.Fail(&aisuite.Error{Category: aisuite.ErrorCategoryRateLimit, StatusCode: 429})))
+	_, err := client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{Model: "openai:gpt-4o-mini"})
+	if err == nil {
+		t.Fatal("want error")
+	}
+	span := exporter.GetSpans()[0]
+	if span.Status.Code != codes.Error || attributes(span)[errorTypeKey].AsString() != "rate_limit" {
+		t.Errorf("unexpected span status %v, attributes %v", span.Status, span.Attributes)
+	}
+
+	client, exporter = newTestClient(mock.NewClient(mock.Fail(errors.New("boom"))))
+	_, _ = client.ChatCompletion(context.Background(), aisuite.ChatCompletionRequest{Model: "openai:gpt-4o-mini"})
+	if got := attributes(exporter.GetSpans()[0])[errorTypeKey].AsString(); got != "*errors.errorString" {
+		t.Errorf("got error type %q", got)
+	}
+}
+
+func TestStreamChatCompletion(t *testing.T) {
+	client, exporter := newTestClient(mock.NewClient(replyChunks()), WithCaptureContent(true))
+	stream, err := client.StreamChatCompletion(context.Background(), aisuite.ChatCompletionRequest{
+		Model:    "anthropic:claude-3-5-haiku-20241022",
+		Messages: []aisuite.ChatCompletionMessage{{Role: aisuite.RoleUser, Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = aisuite.AccumulateStream(stream, nil); err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	attrs := attributes(span)
+	if attrs[systemKey].AsString() != "anthropic" ||
+		attrs[responseIDKey].AsString() != "msg_1" ||
+		attrs[finishReasonsKey].AsStringSlice()[0] != "max_tokens" ||
+		attrs[inputTokensKey].AsInt64() != 10 ||
+		attrs[outputTokensKey].AsInt64() != 2 {
+		t.Errorf("unexpected attributes %v", span.Attributes)
+	}
+	var events []string
+	for _, event := range span.Events {
+		events = append(events, event.Name)
+		for _, kv := range event.Attributes {
+			switch kv.Key {
+			case promptKey:
+				if kv.Value.AsString() != `[{"role":"user","content":"Hi"}]` {
+					t.Errorf("got prompt %s", kv.Value.AsString())
+				}
+			case completionKey:
+				if kv.Value.AsString() != `[{"role":"assistant","content":"Hello!"}]` {
+					t.Errorf("got completion %s", kv.Value.AsString())
+				}
+			}
+		}
+	}
+	if got := strings.Join(events, ","); got != "gen_ai.content.prompt,gen_ai.first_token,gen_ai.content.completion" {
+		t.Errorf("got events %s", got)
+	}
+}